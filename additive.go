@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The manifest mode that turns on --no-delete
+const modeAdditive = "additive"
+
+// Marks destination-only labels read-only when deletes are off, so they
+// can't be unchecked, edited or merged away
+func markReadOnly(items []PickerItem) {
+	if !noDelete {
+		return
+	}
+	for i := range items {
+		if items[i].IsDestOnly {
+			items[i].ReadOnly = true
+		}
+	}
+}
+
+// Rejects a summary that deletes or merges away a destination label
+// while --no-delete is set
+func checkNoDelete(summary ActionSummary) error {
+	if !noDelete {
+		return nil
+	}
+
+	var names []string
+	for _, label := range summary.ToDelete {
+		names = append(names, label.Name)
+	}
+	for _, merge := range summary.ToMerge {
+		names = append(names, merge.From)
+	}
+
+	if len(names) > 0 {
+		return fmt.Errorf("refusing to delete labels with --no-delete: %s", strings.Join(names, ", "))
+	}
+	return nil
+}
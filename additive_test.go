@@ -0,0 +1,91 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// Turns on --no-delete for one test
+func useNoDelete(t *testing.T) {
+	t.Helper()
+	old := noDelete
+	noDelete = true
+	t.Cleanup(func() { noDelete = old })
+}
+
+func TestNoDeleteKeepsDestinationLabels(t *testing.T) {
+	useNoDelete(t)
+
+	oldPrune := prune
+	defer func() { prune = oldPrune }()
+	prune = true
+
+	source := Manifest{
+		Labels: []Label{{Name: "bug", Color: "d73a4a"}},
+		Merges: map[string]string{"defect": "bug"},
+	}
+	destLabels := []Label{
+		{Name: "bug", Color: "ee0701"},
+		{Name: "defect", Color: "ededed"},
+		{Name: "stale", Color: "ffffff"},
+	}
+
+	items := prepareItems(source, destLabels)
+	for _, item := range items {
+		if item.IsDestOnly && (!item.ReadOnly || !item.Selected) {
+			t.Errorf("%s should be read-only and kept, got %+v", item.Label.Name, item)
+		}
+	}
+
+	summary := summarize(items, destLabels)
+	if len(summary.ToDelete) != 0 || len(summary.ToMerge) != 0 {
+		t.Errorf("Nothing should be deleted or merged: %+v", summary)
+	}
+	if len(summary.ToUpdate) != 1 || summary.ToUpdate[0].Name != "bug" {
+		t.Errorf("bug should still be updated, got %+v", summary.ToUpdate)
+	}
+}
+
+func TestApplyRejectsDeletesWithNoDelete(t *testing.T) {
+	ms := useMemStore(t)
+	ms.labels["owner/repo"] = []Label{{Name: "stale", Color: "ffffff"}, {Name: "defect", Color: "ededed"}}
+	useNoDelete(t)
+
+	summary := ActionSummary{
+		ToCreate: []Label{{Name: "bug", Color: "d73a4a"}},
+		ToDelete: []Label{{Name: "stale", Color: "ffffff"}},
+		ToMerge:  []LabelMerge{{From: "defect", Into: Label{Name: "bug", Color: "d73a4a"}}},
+	}
+
+	var out strings.Builder
+	err := applyChangesTo(&out, summary, "owner/repo")
+	if err == nil || err.Error() != "refusing to delete labels with --no-delete: stale, defect" {
+		t.Fatalf("Expected the apply to be refused, got %v", err)
+	}
+	if len(ms.labels["owner/repo"]) != 2 {
+		t.Error("Nothing should have been changed")
+	}
+}
+
+func TestPickerReadOnlyItems(t *testing.T) {
+	items := testPickerItems()
+	items[0].ReadOnly = true
+	items[1].ReadOnly = true
+	m := newPickerModel(items, "owner/repo", false)
+
+	pressKeys(m, " ")
+	if !strings.Contains(m.message, "deletes are off") {
+		t.Errorf("Expected a --no-delete message, got %q", m.message)
+	}
+
+	pressKeys(m, "a")
+	if !m.items[0].Selected || !m.items[1].Selected {
+		t.Error("Read-only items should stay selected")
+	}
+	if m.items[2].Selected {
+		t.Error("a should still toggle the other items")
+	}
+	if !strings.Contains(formatPickerItem(m.items[0], false), "[locked: no delete]") {
+		t.Error("Read-only items should be shown as locked")
+	}
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// Ways a repo can drift from the source
+const (
+	driftMissing = "missing" // In the source, not the repo
+	driftChanged = "changed" // Different color or description
+	driftRename  = "rename"  // Still has a label the source renames
+	driftMerge   = "merge"   // Still has a label the source merges away
+	driftExtra   = "extra"   // In the repo, not the source
+)
+
+// DriftItem is one way a repo differs from the source
+type DriftItem struct {
+	Kind    string `json:"kind"`
+	Name    string `json:"name"`
+	Current *Label `json:"current,omitempty"` // What the repo has
+	Want    *Label `json:"want,omitempty"`    // What the source has
+}
+
+// DriftReport is how one repo compares with the source
+type DriftReport struct {
+	Repo  string      `json:"repo"`
+	Drift []DriftItem `json:"drift"`
+	Error string      `json:"error,omitempty"`
+}
+
+// Calculates what it would take to make a repo match the source exactly,
+// including the source's renames and merges. Protected labels, which sync
+// never touches, don't count, and with --ignore-extra neither do labels
+// only the repo has.
+func checkSummary(source Manifest, destLabels []Label) ActionSummary {
+	summary := calculateActions(source.Labels, destLabels)
+	summary = applyRenames(summary, lowerKeys(source.Renames))
+	summary = applyMerges(summary, lowerKeys(source.Merges))
+	summary = dropProtected(summary, destLabels)
+
+	// An additive sync never deletes, so it keeps labels only the repo has
+	// and the labels it would otherwise merge away
+	additive := noDelete || source.Additive
+	if additive {
+		for _, merge := range summary.ToMerge {
+			if i := indexOfLabel(destLabels, merge.From); i >= 0 {
+				summary.ToKeep = append(summary.ToKeep, destLabels[i])
+			}
+		}
+		summary.ToMerge = []LabelMerge{}
+	}
+
+	if ignoreExtra || additive {
+		summary.ToKeep = append(summary.ToKeep, summary.ToDelete...)
+		summary.ToDelete = []Label{}
+	}
+	return summary
+}
+
+// Returns a copy of a name mapping keyed by lowercase name
+func lowerKeys(m map[string]string) map[string]string {
+	lower := make(map[string]string, len(m))
+	for key, value := range m {
+		lower[strings.ToLower(key)] = value
+	}
+	return lower
+}
+
+// Lists the drift in a summary
+func driftItems(summary ActionSummary, destLabels []Label) []DriftItem {
+	current := func(name string) *Label {
+		if i := indexOfLabel(destLabels, name); i >= 0 {
+			label := destLabels[i]
+			return &label
+		}
+		return nil
+	}
+
+	items := []DriftItem{}
+	for _, label := range summary.ToCreate {
+		want := label
+		items = append(items, DriftItem{Kind: driftMissing, Name: label.Name, Want: &want})
+	}
+	for _, label := range summary.ToUpdate {
+		want := label
+		items = append(items, DriftItem{Kind: driftChanged, Name: label.Name, Current: current(label.Name), Want: &want})
+	}
+	for _, rename := range summary.ToRename {
+		want := rename.To
+		items = append(items, DriftItem{Kind: driftRename, Name: rename.From, Current: current(rename.From), Want: &want})
+	}
+	for _, merge := range summary.ToMerge {
+		want := merge.Into
+		items = append(items, DriftItem{Kind: driftMerge, Name: merge.From, Current: current(merge.From), Want: &want})
+	}
+	for _, label := range summary.ToDelete {
+		have := label
+		items = append(items, DriftItem{Kind: driftExtra, Name: label.Name, Current: &have})
+	}
+	return items
+}
+
+// Compares every destination with the source, --parallel at a time
+func checkRepos(source Manifest, dests []string) []DriftReport {
+	reports := make([]DriftReport, len(dests))
+	forEachParallel(len(dests), parallel, func(i int) {
+		reports[i].Repo = dests[i]
+
+		destLabels, err := FetchLabels(dests[i])
+		if err != nil {
+			reports[i].Error = err.Error()
+			return
+		}
+		reports[i].Drift = driftItems(checkSummary(source, destLabels), destLabels)
+	})
+	return reports
+}
+
+// Describes a drift item on one line
+func describeDrift(item DriftItem) string {
+	switch item.Kind {
+	case driftMissing:
+		return fmt.Sprintf("missing  %s", diffLine(*item.Want))
+	case driftChanged:
+		return fmt.Sprintf("changed  %s → %s", diffLine(*item.Current), diffLine(*item.Want))
+	case driftRename:
+		return fmt.Sprintf("rename   %s → %s", item.Name, item.Want.Name)
+	case driftMerge:
+		return fmt.Sprintf("merge    %s into %s", item.Name, item.Want.Name)
+	default:
+		return fmt.Sprintf("extra    %s", diffLine(*item.Current))
+	}
+}
+
+// Prints the drift of every repo and a total
+func printDriftReport(w io.Writer, reports []DriftReport) {
+	drifted, failed := 0, 0
+	for _, report := range reports {
+		switch {
+		case report.Error != "":
+			failed++
+			fmt.Fprintf(w, "%s: error: %s\n", report.Repo, firstLine(report.Error))
+		case len(report.Drift) == 0:
+			fmt.Fprintf(w, "%s: up to date\n", report.Repo)
+		default:
+			drifted++
+			fmt.Fprintf(w, "%s: drifted\n", report.Repo)
+			for _, item := range report.Drift {
+				fmt.Fprintf(w, "    %s\n", describeDrift(item))
+			}
+		}
+	}
+
+	fmt.Fprintf(w, "\n%d of %d repos drifted", drifted, len(reports))
+	if failed > 0 {
+		fmt.Fprintf(w, ", %d could not be checked", failed)
+	}
+	fmt.Fprintln(w)
+}
+
+// Writes the reports as an indented JSON array
+func writeDriftJSON(w io.Writer, reports []DriftReport) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(reports)
+}
+
+// JUnit XML, the report format most CI systems can show
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Name     string       `xml:"name,attr"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Errors   int          `xml:"errors,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Errors   int         `xml:"errors,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Failure   *junitProblem `xml:"failure,omitempty"`
+	Error     *junitProblem `xml:"error,omitempty"`
+}
+
+type junitProblem struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// Writes the reports as JUnit XML, one test case per repo. Drift is a
+// failure and a repo that couldn't be checked is an error.
+func writeDriftJUnit(w io.Writer, source string, reports []DriftReport) error {
+	suite := junitSuite{Name: source, Tests: len(reports)}
+	for _, report := range reports {
+		testCase := junitCase{Name: report.Repo, Classname: "gabel.check"}
+		switch {
+		case report.Error != "":
+			suite.Errors++
+			testCase.Error = &junitProblem{Message: firstLine(report.Error), Text: report.Error}
+		case len(report.Drift) > 0:
+			suite.Failures++
+			lines := make([]string, 0, len(report.Drift))
+			for _, item := range report.Drift {
+				lines = append(lines, describeDrift(item))
+			}
+			message := fmt.Sprintf("%d labels drifted from %s", len(report.Drift), source)
+			if len(report.Drift) == 1 {
+				message = fmt.Sprintf("1 label drifted from %s", source)
+			}
+			testCase.Failure = &junitProblem{Message: message, Text: strings.Join(lines, "\n")}
+		}
+		suite.Cases = append(suite.Cases, testCase)
+	}
+
+	suites := junitSuites{
+		Name:     "gabel check",
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Errors:   suite.Errors,
+		Suites:   []junitSuite{suite},
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(suites); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// Returns the exit code for a check: errors outrank drift
+func checkExitCode(reports []DriftReport) int {
+	code := exitMatches
+	for _, report := range reports {
+		if report.Error != "" {
+			return exitCompareError
+		}
+		if len(report.Drift) > 0 {
+			code = exitDiffers
+		}
+	}
+	return code
+}
+
+// Reports which destinations have drifted from the source, without
+// changing anything
+func runCheck(cmd *cobra.Command, args []string) {
+	sourceRepo := args[0]
+
+	InitLogger(debug)
+
+	if outputFormat != "text" && outputFormat != "json" && outputFormat != "junit" {
+		fmt.Fprintf(os.Stderr, "Error: Invalid output format %q. Use 'text', 'json' or 'junit'.\n", outputFormat)
+		os.Exit(exitCompareError)
+	}
+
+	dests, err := destinationArgs(args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitCompareError)
+	}
+	if !isValidSource(sourceRepo) {
+		fmt.Fprintf(os.Stderr, "Error: Invalid repo format. Use 'owner/repo' or 'HOST/owner/repo' format.\n")
+		os.Exit(exitCompareError)
+	}
+	for _, dest := range dests {
+		if !isValidRepo(dest) {
+			fmt.Fprintf(os.Stderr, "Error: Invalid repo format. Use 'owner/repo' or 'HOST/owner/repo' format.\n")
+			os.Exit(exitCompareError)
+		}
+	}
+
+	dests = uniqueDestinations(dests, sourceRepo)
+	if len(dests) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: No destination repos. Pass them as arguments or with --dest-file.\n")
+		os.Exit(exitCompareError)
+	}
+
+	if err := initStore(append(sourceRepos(sourceRepo), dests...)...); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(exitCompareError)
+	}
+
+	fmt.Fprintf(os.Stderr, "Fetching labels from %s...\n", sourceRepo)
+	source, err := loadSource(sourceRepo)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error fetching labels from %s: %v\n", sourceRepo, err)
+		os.Exit(exitCompareError)
+	}
+	printSourceConflicts(os.Stderr, source.Conflicts)
+	protect = append(protect, source.Protected...)
+
+	fmt.Fprintf(os.Stderr, "Checking %d repos...\n", len(dests))
+	reports := checkRepos(source, dests)
+
+	switch outputFormat {
+	case "json":
+		err = writeDriftJSON(os.Stdout, reports)
+	case "junit":
+		err = writeDriftJUnit(os.Stdout, sourceRepo, reports)
+	default:
+		printDriftReport(os.Stdout, reports)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitCompareError)
+	}
+
+	os.Exit(checkExitCode(reports))
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestCheckRepos(t *testing.T) {
+	ms := useMemStore(t)
+	ms.labels["owner/current"] = []Label{{Name: "bug", Color: "d73a4a"}, {Name: "docs", Color: "0075ca"}}
+	ms.labels["owner/drifted"] = []Label{
+		{Name: "bug", Color: "ee0701"},
+		{Name: "documentation", Color: "0075ca"},
+		{Name: "defect", Color: "ff0000"},
+		{Name: "wontfix", Color: "ffffff"},
+	}
+
+	source := Manifest{
+		Labels:  []Label{{Name: "bug", Color: "d73a4a"}, {Name: "docs", Color: "0075ca"}},
+		Renames: map[string]string{"Documentation": "docs"},
+		Merges:  map[string]string{"defect": "bug"},
+	}
+
+	reports := checkRepos(source, []string{"owner/current", "owner/drifted", "owner/missing"})
+
+	if len(reports[0].Drift) != 0 || reports[0].Error != "" {
+		t.Errorf("owner/current should be up to date, got %+v", reports[0])
+	}
+
+	var kinds []string
+	for _, item := range reports[1].Drift {
+		kinds = append(kinds, item.Kind+" "+item.Name)
+	}
+	want := "changed bug, rename documentation, merge defect, extra wontfix"
+	if got := strings.Join(kinds, ", "); got != want {
+		t.Errorf("Drift = %s, want %s", got, want)
+	}
+
+	if reports[2].Error == "" {
+		t.Error("A repo that can't be fetched should report an error")
+	}
+	if code := checkExitCode(reports); code != exitCompareError {
+		t.Errorf("Exit code = %d, want %d when a repo failed", code, exitCompareError)
+	}
+	if code := checkExitCode(reports[:2]); code != exitDiffers {
+		t.Errorf("Exit code = %d, want %d on drift", code, exitDiffers)
+	}
+	if code := checkExitCode(reports[:1]); code != exitMatches {
+		t.Errorf("Exit code = %d, want %d when every repo matches", code, exitMatches)
+	}
+}
+
+func TestCheckIgnoreExtra(t *testing.T) {
+	ignoreExtra = true
+	t.Cleanup(func() { ignoreExtra = false })
+
+	source := Manifest{Labels: []Label{{Name: "bug", Color: "d73a4a"}}}
+	dest := []Label{{Name: "bug", Color: "d73a4a"}, {Name: "wontfix", Color: "ffffff"}}
+
+	if drift := driftItems(checkSummary(source, dest), dest); len(drift) != 0 {
+		t.Errorf("Extra labels should not count with --ignore-extra, got %+v", drift)
+	}
+}
+
+func TestPrintDriftReport(t *testing.T) {
+	reports := []DriftReport{
+		{Repo: "owner/a", Drift: []DriftItem{}},
+		{Repo: "owner/b", Drift: []DriftItem{
+			{Kind: driftMissing, Name: "docs", Want: &Label{Name: "docs", Color: "0075ca"}},
+			{Kind: driftChanged, Name: "bug", Current: &Label{Name: "bug", Color: "ee0701"}, Want: &Label{Name: "bug", Color: "d73a4a"}},
+			{Kind: driftExtra, Name: "wontfix", Current: &Label{Name: "wontfix", Color: "ffffff"}},
+		}},
+		{Repo: "owner/c", Error: "Not Found (HTTP 404)"},
+	}
+
+	var out bytes.Buffer
+	printDriftReport(&out, reports)
+
+	want := `owner/a: up to date
+owner/b: drifted
+    missing  docs #0075ca
+    changed  bug #ee0701 → bug #d73a4a
+    extra    wontfix #ffffff
+owner/c: error: Not Found (HTTP 404)
+
+1 of 3 repos drifted, 1 could not be checked
+`
+	if out.String() != want {
+		t.Errorf("Report:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestWriteDriftJUnit(t *testing.T) {
+	reports := []DriftReport{
+		{Repo: "owner/a", Drift: []DriftItem{}},
+		{Repo: "owner/b", Drift: []DriftItem{{Kind: driftExtra, Name: "wontfix", Current: &Label{Name: "wontfix", Color: "ffffff"}}}},
+		{Repo: "owner/c", Error: "Not Found (HTTP 404)"},
+	}
+
+	var out bytes.Buffer
+	if err := writeDriftJUnit(&out, "owner/source", reports); err != nil {
+		t.Fatal(err)
+	}
+
+	var suites junitSuites
+	if err := xml.Unmarshal(out.Bytes(), &suites); err != nil {
+		t.Fatalf("Invalid XML: %v\n%s", err, out.String())
+	}
+	if suites.Tests != 3 || suites.Failures != 1 || suites.Errors != 1 {
+		t.Errorf("Totals = %d tests, %d failures, %d errors", suites.Tests, suites.Failures, suites.Errors)
+	}
+
+	cases := suites.Suites[0].Cases
+	if cases[0].Failure != nil || cases[0].Error != nil {
+		t.Errorf("owner/a should pass, got %+v", cases[0])
+	}
+	if cases[1].Failure == nil || cases[1].Failure.Message != "1 label drifted from owner/source" || !strings.Contains(cases[1].Failure.Text, "extra    wontfix") {
+		t.Errorf("owner/b should fail with its drift, got %+v", cases[1].Failure)
+	}
+	if cases[2].Error == nil || cases[2].Error.Message != "Not Found (HTTP 404)" {
+		t.Errorf("owner/c should be an error, got %+v", cases[2])
+	}
+}
+
+func TestCheckSkipsProtected(t *testing.T) {
+	useProtect(t, "dependencies", "release/*")
+
+	source := Manifest{
+		Labels:  []Label{{Name: "bug", Color: "d73a4a"}, {Name: "release", Color: "ededed"}, {Name: "dependencies", Color: "0366d6"}},
+		Renames: map[string]string{"release/old": "release"},
+	}
+	dest := []Label{
+		{Name: "bug", Color: "d73a4a"},
+		{Name: "dependencies", Color: "ffffff"}, // Bot-owned, with its own color
+		{Name: "release/old", Color: "ededed"},
+		{Name: "release/v1", Color: "ededed"},
+		{Name: "wontfix", Color: "ffffff"},
+	}
+
+	var kinds []string
+	for _, item := range driftItems(checkSummary(source, dest), dest) {
+		kinds = append(kinds, item.Kind+" "+item.Name)
+	}
+	// The protected label can't be renamed, so sync adds the new one
+	want := "missing release, extra wontfix"
+	if got := strings.Join(kinds, ", "); got != want {
+		t.Errorf("Drift = %s, want %s", got, want)
+	}
+}
+
+func TestCheckAdditive(t *testing.T) {
+	source := Manifest{
+		Labels: []Label{{Name: "bug", Color: "d73a4a"}, {Name: "docs", Color: "0075ca"}},
+		Merges: map[string]string{"defect": "bug"},
+	}
+	dest := []Label{
+		{Name: "bug", Color: "ee0701"},
+		{Name: "defect", Color: "ff0000"},
+		{Name: "wontfix", Color: "ffffff"},
+	}
+
+	driftOf := func() string {
+		var kinds []string
+		for _, item := range driftItems(checkSummary(source, dest), dest) {
+			kinds = append(kinds, item.Kind+" "+item.Name)
+		}
+		return strings.Join(kinds, ", ")
+	}
+
+	// mode: additive in the labels file
+	source.Additive = true
+	if got, want := driftOf(), "missing docs, changed bug"; got != want {
+		t.Errorf("Additive drift = %s, want %s", got, want)
+	}
+
+	// --no-delete
+	source.Additive = false
+	useNoDelete(t)
+	if got, want := driftOf(), "missing docs, changed bug"; got != want {
+		t.Errorf("--no-delete drift = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/manifoldco/promptui"
+)
+
+// Reports whether a plan deletes enough labels that the destination repo's
+// name must be typed to confirm it
+func needsTypedConfirm(summary ActionSummary) bool {
+	return confirmThreshold > 0 && len(summary.ToDelete) >= confirmThreshold
+}
+
+// Lists every label a plan deletes, with the issues and PRs still using it
+func printDeletions(w io.Writer, summary ActionSummary, usage map[string]LabelUses) {
+	if len(summary.ToDelete) == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "\n  Labels to delete (%d):\n", len(summary.ToDelete))
+	inUse := false
+	for _, label := range summary.ToDelete {
+		line := FormatLabel(label, false)
+		if uses := usesOf(usage, label.Name); uses.inUse() {
+			line += "  " + formatUses(uses)
+			inUse = true
+		}
+		fmt.Fprintf(w, "    %s\n", line)
+	}
+	if inUse {
+		fmt.Fprintf(w, "  [WARN] Deleting a label removes it from every issue and PR that has it\n")
+	}
+}
+
+// Checks a typed confirmation against the destination repo
+func checkRepoName(input, destRepo string) error {
+	if strings.TrimSpace(input) != destRepo {
+		return fmt.Errorf("type %s to confirm", destRepo)
+	}
+	return nil
+}
+
+// Asks for the destination repo's name before a destructive apply, the
+// way GitHub's danger zone does
+func confirmRepoName(destRepo string, deletes int) error {
+	noun := "labels"
+	if deletes == 1 {
+		noun = "label"
+	}
+	prompt := promptui.Prompt{
+		Label:    fmt.Sprintf("Deleting %d %s. Type %s to confirm", deletes, noun, destRepo),
+		Validate: func(input string) error { return checkRepoName(input, destRepo) },
+	}
+	if _, err := prompt.Run(); err != nil {
+		return fmt.Errorf("cancelled")
+	}
+	return nil
+}
+
+// Lists the deletions of each repo in a multi-repo run whose plan needs a
+// typed confirmation, and asks for that repo's name before going on
+func confirmMultiDeletes(w io.Writer, results []repoResult, confirm func(repo string, deletes int) error) error {
+	for _, result := range results {
+		if result.Err != nil || !needsTypedConfirm(result.Summary) {
+			continue
+		}
+
+		// Without usage the list still shows, with every count unknown
+		usage, err := fetchUsage(result.Repo, result.Summary.ToDelete)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: couldn't count label usage in %s: %v\n", result.Repo, err)
+		}
+		fmt.Fprintf(w, "\n── %s ──", result.Repo)
+		printDeletions(w, result.Summary, usage)
+
+		if err := confirm(result.Repo, len(result.Summary.ToDelete)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestNeedsTypedConfirm(t *testing.T) {
+	old := confirmThreshold
+	defer func() { confirmThreshold = old }()
+
+	twoDeletes := ActionSummary{ToDelete: []Label{{Name: "stale"}, {Name: "wontfix"}}}
+	tests := []struct {
+		threshold int
+		summary   ActionSummary
+		want      bool
+	}{
+		{1, twoDeletes, true},
+		{2, twoDeletes, true},
+		{3, twoDeletes, false},
+		{0, twoDeletes, false},
+		{1, ActionSummary{ToCreate: []Label{{Name: "bug"}}}, false},
+	}
+
+	for _, tt := range tests {
+		confirmThreshold = tt.threshold
+		if got := needsTypedConfirm(tt.summary); got != tt.want {
+			t.Errorf("needsTypedConfirm() with threshold %d = %v, want %v", tt.threshold, got, tt.want)
+		}
+	}
+}
+
+func TestPrintDeletions(t *testing.T) {
+	summary := ActionSummary{ToDelete: []Label{
+		{Name: "stale", Color: "ffffff"},
+		{Name: "wontfix", Color: "ffffff"},
+		{Name: "invalid", Color: "e4e669"},
+	}}
+	usage := map[string]LabelUses{"stale": {}, "wontfix": {OpenIssues: 2, ClosedPRs: 1}}
+
+	var out strings.Builder
+	printDeletions(&out, summary, usage)
+
+	got := out.String()
+	for _, want := range []string{
+		"Labels to delete (3):",
+		"wontfix",
+		"2 open issues, 1 closed PR",
+		"usage unknown",
+		"[WARN] Deleting a label removes it",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Output should contain %q, got:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "unused") {
+		t.Errorf("Unused labels should not show a count:\n%s", got)
+	}
+
+	out.Reset()
+	printDeletions(&out, ActionSummary{}, nil)
+	if out.Len() != 0 {
+		t.Errorf("Nothing should be printed without deletions, got %q", out.String())
+	}
+}
+
+func TestCheckRepoName(t *testing.T) {
+	if err := checkRepoName("owner/repo", "owner/repo"); err != nil {
+		t.Errorf("Exact name should confirm, got %v", err)
+	}
+	if err := checkRepoName(" owner/repo ", "owner/repo"); err != nil {
+		t.Errorf("Surrounding spaces should be ignored, got %v", err)
+	}
+	for _, input := range []string{"", "y", "repo", "owner/other"} {
+		if err := checkRepoName(input, "owner/repo"); err == nil {
+			t.Errorf("%q should not confirm owner/repo", input)
+		}
+	}
+}
+
+func TestConfirmMultiDeletes(t *testing.T) {
+	m := useMemStore(t)
+	m.usage["myorg/a"] = map[string]LabelUses{"wontfix": {ClosedIssues: 3}}
+
+	old := confirmThreshold
+	defer func() { confirmThreshold = old }()
+	confirmThreshold = 2
+
+	results := []repoResult{
+		{Repo: "myorg/a", Summary: ActionSummary{ToDelete: []Label{{Name: "stale"}, {Name: "wontfix"}}}},
+		{Repo: "myorg/b", Summary: ActionSummary{ToDelete: []Label{{Name: "stale"}}}},
+		{Repo: "myorg/c", Summary: ActionSummary{ToCreate: []Label{{Name: "bug"}}}},
+		{Repo: "myorg/d", Summary: ActionSummary{ToDelete: []Label{{Name: "stale"}, {Name: "old"}, {Name: "dup"}}}},
+		{Repo: "myorg/e", Err: &APIError{Kind: ErrNotFound}},
+	}
+
+	var out strings.Builder
+	var asked []string
+	err := confirmMultiDeletes(&out, results, func(repo string, deletes int) error {
+		asked = append(asked, fmt.Sprintf("%s:%d", repo, deletes))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("confirmMultiDeletes() error = %v", err)
+	}
+	if got := strings.Join(asked, " "); got != "myorg/a:2 myorg/d:3" {
+		t.Errorf("Asked for %q, want only the repos over the threshold", got)
+	}
+
+	got := out.String()
+	for _, want := range []string{"── myorg/a ──", "Labels to delete (2):", "3 closed issues", "── myorg/d ──", "Labels to delete (3):"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Output should contain %q, got:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "myorg/b") {
+		t.Errorf("Repos under the threshold should not be listed:\n%s", got)
+	}
+
+	// Refusing one repo stops before asking about the rest
+	asked = nil
+	err = confirmMultiDeletes(io.Discard, results, func(repo string, deletes int) error {
+		asked = append(asked, repo)
+		return fmt.Errorf("cancelled")
+	})
+	if err == nil || len(asked) != 1 {
+		t.Errorf("Expected the first refusal to cancel, got %v after %v", err, asked)
+	}
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+// Exit codes of gabel diff and gabel check, which follow diff(1) rather
+// than the rest of gabel
+const (
+	exitMatches      = 0
+	exitDiffers      = 1
+	exitCompareError = 2
+)
+
+// How a label compares between source and destination
+const (
+	diffSourceOnly = "only-in-source"
+	diffDestOnly   = "only-in-dest"
+	diffIdentical  = "identical"
+	diffDiffers    = "differs"
+)
+
+// LabelDiff compares one label, matched case-insensitively, between repos
+type LabelDiff struct {
+	Name    string   `json:"name"`
+	Status  string   `json:"status"`            // only-in-source, only-in-dest, identical or differs
+	Changes []string `json:"changes,omitempty"` // What differs: color, description and case
+	Source  *Label   `json:"source,omitempty"`
+	Dest    *Label   `json:"dest,omitempty"`
+}
+
+// DiffReport is the output of gabel diff
+type DiffReport struct {
+	Source string      `json:"source"`
+	Dest   string      `json:"dest"`
+	Labels []LabelDiff `json:"labels"`
+}
+
+// Compares two label sets, matching names case-insensitively like
+// calculateActions does. The result is sorted by name.
+func diffLabels(source, dest []Label) []LabelDiff {
+	destMap := make(map[string]Label)
+	for _, label := range dest {
+		destMap[strings.ToLower(label.Name)] = label
+	}
+
+	diffs := []LabelDiff{}
+	seen := make(map[string]bool)
+	for _, label := range source {
+		key := strings.ToLower(label.Name)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		sourceLabel := label
+		destLabel, exists := destMap[key]
+		if !exists {
+			diffs = append(diffs, LabelDiff{Name: label.Name, Status: diffSourceOnly, Source: &sourceLabel})
+			continue
+		}
+
+		diff := LabelDiff{Name: label.Name, Status: diffIdentical, Source: &sourceLabel, Dest: &destLabel}
+		if normalizeColor(label.Color) != normalizeColor(destLabel.Color) {
+			diff.Changes = append(diff.Changes, "color")
+		}
+		if label.Description != destLabel.Description {
+			diff.Changes = append(diff.Changes, "description")
+		}
+		if label.Name != destLabel.Name {
+			diff.Changes = append(diff.Changes, "case")
+		}
+		if len(diff.Changes) > 0 {
+			diff.Status = diffDiffers
+		}
+		diffs = append(diffs, diff)
+	}
+
+	for _, label := range dest {
+		key := strings.ToLower(label.Name)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		destLabel := label
+		diffs = append(diffs, LabelDiff{Name: label.Name, Status: diffDestOnly, Dest: &destLabel})
+	}
+
+	sort.SliceStable(diffs, func(i, j int) bool {
+		return strings.ToLower(diffs[i].Name) < strings.ToLower(diffs[j].Name)
+	})
+	return diffs
+}
+
+// Reports whether any label is out of sync. Labels that only differ in
+// case are reported but don't count: like calculateActions, sync treats
+// them as the same label and leaves the name alone.
+func hasDifferences(diffs []LabelDiff) bool {
+	for _, diff := range diffs {
+		if diff.Status != diffIdentical && !caseOnly(diff) {
+			return true
+		}
+	}
+	return false
+}
+
+// Reports whether a label only differs in the case of its name
+func caseOnly(diff LabelDiff) bool {
+	return diff.Status == diffDiffers && !labelsDiffer(*diff.Source, *diff.Dest)
+}
+
+// Returns gabel diff's exit code for a comparison
+func diffExitCode(diffs []LabelDiff) int {
+	if hasDifferences(diffs) {
+		return exitDiffers
+	}
+	return exitMatches
+}
+
+// Prints a report as a unified diff: lines only in the source start with
+// -, lines only in the destination with +, and labels that differ get a
+// @@ line saying how, followed by both versions
+func printDiff(w io.Writer, report DiffReport) {
+	removed := color.New(color.FgRed)
+	added := color.New(color.FgGreen)
+	hunk := color.New(color.FgCyan)
+
+	fmt.Fprintf(w, "%s\n", removed.Sprintf("--- %s", report.Source))
+	fmt.Fprintf(w, "%s\n", added.Sprintf("+++ %s", report.Dest))
+
+	counts := make(map[string]int)
+	caseOnlyCount := 0
+	for _, diff := range report.Labels {
+		counts[diff.Status]++
+		if caseOnly(diff) {
+			caseOnlyCount++
+		}
+		switch diff.Status {
+		case diffIdentical:
+			fmt.Fprintf(w, "  %s\n", diffLine(*diff.Source))
+		case diffSourceOnly:
+			fmt.Fprintf(w, "%s\n", removed.Sprintf("- %s", diffLine(*diff.Source)))
+		case diffDestOnly:
+			fmt.Fprintf(w, "%s\n", added.Sprintf("+ %s", diffLine(*diff.Dest)))
+		case diffDiffers:
+			fmt.Fprintf(w, "%s\n", hunk.Sprintf("@@ %s differs in %s @@", diff.Name, strings.Join(diff.Changes, ", ")))
+			fmt.Fprintf(w, "%s\n", removed.Sprintf("- %s", diffLine(*diff.Source)))
+			fmt.Fprintf(w, "%s\n", added.Sprintf("+ %s", diffLine(*diff.Dest)))
+		}
+	}
+
+	fmt.Fprintf(w, "\n%d only in %s, %d only in %s, %d differ, %d identical\n",
+		counts[diffSourceOnly], report.Source, counts[diffDestOnly], report.Dest, counts[diffDiffers], counts[diffIdentical])
+	if caseOnlyCount > 0 {
+		fmt.Fprintf(w, "%d differ only in case, which syncing leaves as is\n", caseOnlyCount)
+	}
+}
+
+// Formats a label as one line of a diff
+func diffLine(label Label) string {
+	line := fmt.Sprintf("%s #%s", label.Name, normalizeColor(label.Color))
+	if label.Description != "" {
+		line += fmt.Sprintf("  %q", label.Description)
+	}
+	return line
+}
+
+// Writes a report as indented JSON
+func writeDiffJSON(w io.Writer, report DiffReport) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
+
+// Compares a source's labels with a destination's without changing anything
+func runDiff(cmd *cobra.Command, args []string) {
+	sourceRepo, destRepo := args[0], args[1]
+
+	InitLogger(debug)
+
+	if outputFormat != "text" && outputFormat != "json" {
+		fmt.Fprintf(os.Stderr, "Error: Invalid output format %q. Use 'text' or 'json'.\n", outputFormat)
+		os.Exit(exitCompareError)
+	}
+	if !isValidSource(sourceRepo) || !isValidRepo(destRepo) {
+		fmt.Fprintf(os.Stderr, "Error: Invalid repo format. Use 'owner/repo' or 'HOST/owner/repo' format.\n")
+		os.Exit(exitCompareError)
+	}
+
+	if err := initStore(append(sourceRepos(sourceRepo), destRepo)...); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(exitCompareError)
+	}
+
+	fmt.Fprintf(os.Stderr, "Fetching labels from %s...\n", sourceRepo)
+	source, err := loadSource(sourceRepo)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error fetching labels from %s: %v\n", sourceRepo, err)
+		os.Exit(exitCompareError)
+	}
+	printSourceConflicts(os.Stderr, source.Conflicts)
+
+	fmt.Fprintf(os.Stderr, "Fetching labels from %s...\n", destRepo)
+	destLabels, err := FetchLabels(destRepo)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error fetching labels from %s: %v\n", destRepo, err)
+		os.Exit(exitCompareError)
+	}
+
+	report := DiffReport{Source: sourceRepo, Dest: destRepo, Labels: diffLabels(source.Labels, destLabels)}
+	if outputFormat == "json" {
+		if err := writeDiffJSON(os.Stdout, report); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitCompareError)
+		}
+	} else {
+		printDiff(os.Stdout, report)
+	}
+
+	os.Exit(diffExitCode(report.Labels))
+}
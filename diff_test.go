@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestDiffLabels(t *testing.T) {
+	source := []Label{
+		{Name: "bug", Color: "d73a4a", Description: "Something isn't working"},
+		{Name: "Docs", Color: "0075ca"},
+		{Name: "enhancement", Color: "a2eeef"},
+		{Name: "question", Color: "d876e3", Description: "Further information is requested"},
+	}
+	dest := []Label{
+		{Name: "bug", Color: "#D73A4A", Description: "Something isn't working"},
+		{Name: "docs", Color: "0075ca"},
+		{Name: "question", Color: "cc317c", Description: "Needs an answer"},
+		{Name: "wontfix", Color: "ffffff"},
+	}
+
+	diffs := diffLabels(source, dest)
+
+	want := []struct {
+		name    string
+		status  string
+		changes string
+	}{
+		{"bug", diffIdentical, ""},
+		{"Docs", diffDiffers, "case"},
+		{"enhancement", diffSourceOnly, ""},
+		{"question", diffDiffers, "color,description"},
+		{"wontfix", diffDestOnly, ""},
+	}
+	if len(diffs) != len(want) {
+		t.Fatalf("Expected %d diffs, got %+v", len(want), diffs)
+	}
+	for i, w := range want {
+		d := diffs[i]
+		if d.Name != w.name || d.Status != w.status || strings.Join(d.Changes, ",") != w.changes {
+			t.Errorf("diffs[%d] = %s %s %v, want %s %s %s", i, d.Name, d.Status, d.Changes, w.name, w.status, w.changes)
+		}
+	}
+
+	if !hasDifferences(diffs) {
+		t.Error("Expected differences")
+	}
+	if hasDifferences(diffLabels(source[:1], dest[:1])) {
+		t.Error("Identical labels should not count as differences")
+	}
+}
+
+func TestDiffCaseOnlyAfterSync(t *testing.T) {
+	source := []Label{{Name: "Bug", Color: "d73a4a"}, {Name: "docs", Color: "0075ca"}}
+	dest := []Label{{Name: "bug", Color: "d73a4a"}, {Name: "docs", Color: "0075ca"}}
+
+	// Sync has nothing left to do
+	if hasChanges(calculateActions(source, dest)) {
+		t.Fatal("A case-only difference should not need syncing")
+	}
+
+	diffs := diffLabels(source, dest)
+	if diffs[0].Status != diffDiffers || strings.Join(diffs[0].Changes, ",") != "case" {
+		t.Errorf("The case difference should still be reported, got %+v", diffs[0])
+	}
+	if code := diffExitCode(diffs); code != exitMatches {
+		t.Errorf("diffExitCode() = %d, want %d after a sync", code, exitMatches)
+	}
+
+	dest[1].Color = "ffffff"
+	if code := diffExitCode(diffLabels(source, dest)); code != exitDiffers {
+		t.Errorf("diffExitCode() = %d, want %d with a color difference", code, exitDiffers)
+	}
+
+	old := color.NoColor
+	color.NoColor = true
+	defer func() { color.NoColor = old }()
+	var out bytes.Buffer
+	printDiff(&out, DiffReport{Source: "a/b", Dest: "c/d", Labels: diffs})
+	if !strings.Contains(out.String(), "1 differ only in case, which syncing leaves as is") {
+		t.Errorf("The summary should note the case-only difference:\n%s", out.String())
+	}
+}
+
+func TestPrintDiff(t *testing.T) {
+	old := color.NoColor
+	color.NoColor = true
+	defer func() { color.NoColor = old }()
+
+	report := DiffReport{
+		Source: "owner/source",
+		Dest:   "owner/dest",
+		Labels: diffLabels(
+			[]Label{{Name: "bug", Color: "d73a4a"}, {Name: "docs", Color: "0075ca"}, {Name: "question", Color: "d876e3"}},
+			[]Label{{Name: "bug", Color: "d73a4a"}, {Name: "question", Color: "cc317c"}, {Name: "wontfix", Color: "ffffff"}},
+		),
+	}
+
+	var out bytes.Buffer
+	printDiff(&out, report)
+
+	want := `--- owner/source
++++ owner/dest
+  bug #d73a4a
+- docs #0075ca
+@@ question differs in color @@
+- question #d876e3
++ question #cc317c
++ wontfix #ffffff
+
+1 only in owner/source, 1 only in owner/dest, 1 differ, 1 identical
+`
+	if out.String() != want {
+		t.Errorf("printDiff output:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestWriteDiffJSON(t *testing.T) {
+	report := DiffReport{
+		Source: "owner/source",
+		Dest:   "owner/dest",
+		Labels: diffLabels([]Label{{Name: "bug", Color: "d73a4a"}}, []Label{{Name: "Bug", Color: "d73a4a"}}),
+	}
+
+	var out bytes.Buffer
+	if err := writeDiffJSON(&out, report); err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded struct {
+		Labels []struct {
+			Name    string   `json:"name"`
+			Status  string   `json:"status"`
+			Changes []string `json:"changes"`
+			Dest    Label    `json:"dest"`
+		} `json:"labels"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Labels) != 1 || decoded.Labels[0].Status != "differs" || decoded.Labels[0].Changes[0] != "case" || decoded.Labels[0].Dest.Name != "Bug" {
+		t.Errorf("Unexpected JSON:\n%s", out.String())
+	}
+}
@@ -27,6 +27,45 @@ func FormatLabel(label Label, showDescription bool) string {
 	return result
 }
 
+// Formats a label that differs between repos as old → new
+func FormatLabelChange(current, desired Label, showDescription bool) string {
+	oldHex := "#" + strings.TrimPrefix(current.Color, "#")
+	newHex := "#" + strings.TrimPrefix(desired.Color, "#")
+
+	result := fmt.Sprintf("%s %s %s", getColorBlock(newHex), desired.Name, newHex)
+	if normalizeColor(current.Color) != normalizeColor(desired.Color) {
+		result = fmt.Sprintf("%s %s %s → %s %s", getColorBlock(oldHex), desired.Name, oldHex, getColorBlock(newHex), newHex)
+	}
+
+	// Always show a changed description, since it is the difference
+	if current.Description != desired.Description {
+		result += fmt.Sprintf("  %q → %q", truncateDescription(current.Description), truncateDescription(desired.Description))
+	} else if showDescription && desired.Description != "" {
+		result += fmt.Sprintf("  %s", truncateDescription(desired.Description))
+	}
+
+	return result
+}
+
+// Formats a renamed label as old name → new name
+func FormatLabelRename(current, desired Label, showDescription bool) string {
+	oldHex := "#" + strings.TrimPrefix(current.Color, "#")
+	newHex := "#" + strings.TrimPrefix(desired.Color, "#")
+
+	result := fmt.Sprintf("%s %s → %s %s", getColorBlock(oldHex), current.Name, desired.Name, newHex)
+	if normalizeColor(current.Color) != normalizeColor(desired.Color) {
+		result = fmt.Sprintf("%s %s %s → %s %s %s", getColorBlock(oldHex), current.Name, oldHex, getColorBlock(newHex), desired.Name, newHex)
+	}
+
+	if current.Description != desired.Description {
+		result += fmt.Sprintf("  %q → %q", truncateDescription(current.Description), truncateDescription(desired.Description))
+	} else if showDescription && desired.Description != "" {
+		result += fmt.Sprintf("  %s", truncateDescription(desired.Description))
+	}
+
+	return result
+}
+
 // Returns a colored block using fatih/color package
 func getColorBlock(hex string) string {
 	r, g, b := hexToRGB(hex)
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Kinds of GitHub API errors, for use with errors.Is
+var (
+	ErrNotFound    = errors.New("not found")
+	ErrForbidden   = errors.New("forbidden")
+	ErrRateLimited = errors.New("rate limited")
+	ErrValidation  = errors.New("validation failed")
+)
+
+// APIError is an error response from the GitHub API
+type APIError struct {
+	Kind       error // One of the Err* kinds above, or nil
+	StatusCode int
+	Message    string          `json:"message"`
+	Errors     []APIErrorField `json:"errors"`
+	Body       string          // Raw JSON error body
+}
+
+// APIErrorField is one entry of the errors list in a GitHub error body
+type APIErrorField struct {
+	Resource string `json:"resource"`
+	Field    string `json:"field"`
+	Code     string `json:"code"`
+	Message  string `json:"message"`
+}
+
+func (e *APIError) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = http.StatusText(e.StatusCode)
+	}
+
+	var details []string
+	for _, field := range e.Errors {
+		switch {
+		case field.Message != "":
+			details = append(details, field.Message)
+		case field.Field != "":
+			details = append(details, fmt.Sprintf("%s %s", field.Field, field.Code))
+		case field.Code != "":
+			details = append(details, field.Code)
+		}
+	}
+	if len(details) > 0 {
+		msg += ": " + strings.Join(details, ", ")
+	}
+
+	return fmt.Sprintf("%s (HTTP %d)", msg, e.StatusCode)
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Kind
+}
+
+// Builds an APIError from a status code and GitHub's JSON error body.
+// rateLimited reports whether the response headers say the limit was hit.
+func newAPIError(statusCode int, body []byte, rateLimited bool) *APIError {
+	apiErr := &APIError{StatusCode: statusCode, Body: strings.TrimSpace(string(body))}
+	_ = json.Unmarshal(body, apiErr) // Not every error body is JSON
+
+	lowerMessage := strings.ToLower(apiErr.Message)
+	switch {
+	case statusCode == http.StatusTooManyRequests,
+		statusCode == http.StatusForbidden && (rateLimited || strings.Contains(lowerMessage, "rate limit")):
+		apiErr.Kind = ErrRateLimited
+	case statusCode == http.StatusNotFound:
+		apiErr.Kind = ErrNotFound
+	case statusCode == http.StatusUnauthorized, statusCode == http.StatusForbidden:
+		apiErr.Kind = ErrForbidden
+	case statusCode == http.StatusUnprocessableEntity:
+		apiErr.Kind = ErrValidation
+	}
+
+	return apiErr
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// Export formats, all of which can be read back as a source
+var exportFormats = []string{"yaml", "json", "csv", "markdown"}
+
+// Writes a repo's labels to stdout or a file
+func runExport(cmd *cobra.Command, args []string) {
+	source := args[0]
+
+	InitLogger(debug)
+
+	format := exportFormat
+	if !cmd.Flags().Changed("format") && exportFile != "" {
+		format = formatForPath(exportFile)
+	}
+	if !isExportFormat(format) {
+		fmt.Fprintf(os.Stderr, "Error: Invalid format %q. Use one of: %s.\n", format, strings.Join(exportFormats, ", "))
+		os.Exit(1)
+	}
+
+	if !isValidSource(source) {
+		fmt.Fprintf(os.Stderr, "Error: Invalid repo format. Use 'owner/repo' or 'HOST/owner/repo' format.\n")
+		os.Exit(1)
+	}
+
+	if repos := sourceRepos(source); len(repos) > 0 {
+		if err := initStore(repos...); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Fetching labels from %s...\n", source)
+	manifest, err := loadSource(source)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error fetching labels from %s: %v\n", source, err)
+		os.Exit(1)
+	}
+	printSourceConflicts(os.Stderr, manifest.Conflicts)
+
+	out := io.Writer(os.Stdout)
+	var f *os.File
+	if exportFile != "" {
+		f, err = os.Create(exportFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		out = f
+	}
+
+	err = writeLabels(out, sortedLabels(manifest.Labels), format)
+	// Close before exiting, and catch writes that only fail on close
+	if f != nil {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to write labels: %v\n", err)
+		os.Exit(1)
+	}
+
+	if exportFile != "" {
+		fmt.Fprintf(os.Stderr, "Exported %d labels to %s\n", len(manifest.Labels), exportFile)
+	}
+}
+
+// Returns a copy of labels sorted by name, so exports diff cleanly
+func sortedLabels(labels []Label) []Label {
+	sorted := make([]Label, len(labels))
+	copy(sorted, labels)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := strings.ToLower(sorted[i].Name), strings.ToLower(sorted[j].Name)
+		if a != b {
+			return a < b
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+	return sorted
+}
+
+// Guesses the export format from a file extension, defaulting to YAML
+func formatForPath(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return "json"
+	case ".csv":
+		return "csv"
+	case ".md", ".markdown":
+		return "markdown"
+	}
+	return "yaml"
+}
+
+func isExportFormat(format string) bool {
+	for _, f := range exportFormats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// Writes labels in the given format
+func writeLabels(w io.Writer, labels []Label, format string) error {
+	// Colors are written without # like the GitHub API returns them
+	normalized := make([]Label, len(labels))
+	for i, label := range labels {
+		label.Color = normalizeColor(label.Color)
+		normalized[i] = label
+	}
+
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(struct {
+			Labels []Label `json:"labels"`
+		}{normalized})
+	case "csv":
+		return writeLabelsCSV(w, normalized)
+	case "markdown":
+		return writeLabelsMarkdown(w, normalized)
+	}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	defer func() { _ = enc.Close() }()
+	return enc.Encode(struct {
+		Labels []Label `yaml:"labels"`
+	}{normalized})
+}
+
+func writeLabelsCSV(w io.Writer, labels []Label) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"name", "color", "description"})
+	for _, label := range labels {
+		_ = cw.Write([]string{label.Name, label.Color, label.Description})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeLabelsMarkdown(w io.Writer, labels []Label) error {
+	escape := strings.NewReplacer(`\`, `\\`, "|", `\|`)
+
+	if _, err := fmt.Fprintln(w, "| Name | Color | Description |"); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintln(w, "| --- | --- | --- |"); err != nil {
+		return err
+	}
+	for _, label := range labels {
+		_, err := fmt.Fprintf(w, "| %s | `#%s` | %s |\n", escape.Replace(label.Name), label.Color, escape.Replace(label.Description))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
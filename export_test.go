@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSortedLabels(t *testing.T) {
+	labels := []Label{
+		{Name: "feature"},
+		{Name: "Bug"},
+		{Name: "area/ui"},
+		{Name: "bug-fix"},
+	}
+
+	var names []string
+	for _, label := range sortedLabels(labels) {
+		names = append(names, label.Name)
+	}
+
+	want := []string{"area/ui", "Bug", "bug-fix", "feature"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("sortedLabels() = %v, want %v", names, want)
+	}
+
+	if labels[0].Name != "feature" {
+		t.Error("sortedLabels() should not modify its input")
+	}
+}
+
+func TestExportRoundTrip(t *testing.T) {
+	labels := sortedLabels([]Label{
+		{Name: "bug", Color: "#d73a4a", Description: "Something isn't working"},
+		{Name: "black", Color: "000000"},
+		{Name: "needs: triage", Color: "e4e669", Description: `Quotes "and" pipes | and, commas`},
+		{Name: "yes", Color: "1e1e1e", Description: "# not a comment"},
+	})
+
+	extensions := map[string]string{"yaml": ".yaml", "json": ".json", "csv": ".csv", "markdown": ".md"}
+
+	for _, format := range exportFormats {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := writeLabels(&buf, labels, format); err != nil {
+				t.Fatalf("writeLabels() error = %v", err)
+			}
+
+			path := filepath.Join(t.TempDir(), "labels"+extensions[format])
+			if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			manifest, err := loadManifest(path)
+			if err != nil {
+				t.Fatalf("loadManifest() error = %v\n%s", err, buf.String())
+			}
+
+			if len(manifest.Labels) != len(labels) {
+				t.Fatalf("Expected %d labels, got %d", len(labels), len(manifest.Labels))
+			}
+			for i, label := range manifest.Labels {
+				want := labels[i]
+				want.Color = normalizeColor(want.Color)
+				if normalizeColor(label.Color) != want.Color || label.Name != want.Name || label.Description != want.Description {
+					t.Errorf("Label %d = %+v, want %+v", i, label, want)
+				}
+			}
+		})
+	}
+}
+
+func TestWriteLabelsMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeLabels(&buf, []Label{{Name: "bug", Color: "#d73a4a", Description: "a|b"}}, "markdown"); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(buf.String(), "| bug | `#d73a4a` | a\\|b |") {
+		t.Errorf("Unexpected markdown:\n%s", buf.String())
+	}
+}
+
+func TestFormatForPath(t *testing.T) {
+	tests := map[string]string{
+		"labels.json":  "json",
+		"labels.CSV":   "csv",
+		"LABELS.md":    "markdown",
+		"labels.yml":   "yaml",
+		"labels":       "yaml",
+		"out/labels.x": "yaml",
+	}
+
+	for path, want := range tests {
+		if got := formatForPath(path); got != want {
+			t.Errorf("formatForPath(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
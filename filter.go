@@ -0,0 +1,39 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Reports whether every character of the query appears in text, in order
+// and ignoring case, so "enh" matches "enhancement" and "gfi" matches
+// "good first issue"
+func fuzzyMatch(query, text string) bool {
+	text = strings.ToLower(text)
+	for _, r := range strings.ToLower(query) {
+		if unicode.IsSpace(r) {
+			continue
+		}
+		i := strings.IndexRune(text, r)
+		if i < 0 {
+			return false
+		}
+		text = text[i+len(string(r)):]
+	}
+	return true
+}
+
+// Returns the indexes of the items whose name or description matches the
+// query. Renamed items also match on their old name.
+func filterItems(items []PickerItem, query string) []int {
+	visible := make([]int, 0, len(items))
+	for i, item := range items {
+		if query == "" ||
+			fuzzyMatch(query, item.Label.Name) ||
+			fuzzyMatch(query, item.Label.Description) ||
+			(item.Rename && fuzzyMatch(query, item.Current.Name)) {
+			visible = append(visible, i)
+		}
+	}
+	return visible
+}
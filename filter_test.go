@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestFuzzyMatch(t *testing.T) {
+	tests := []struct {
+		query string
+		text  string
+		want  bool
+	}{
+		{"", "bug", true},
+		{"bug", "bug", true},
+		{"BUG", "type: bug", true},
+		{"enh", "enhancement", true},
+		{"gfi", "good first issue", true},
+		{"good issue", "good first issue", true},
+		{"bgu", "bug", false},
+		{"docs", "documentation", false},
+	}
+
+	for _, tt := range tests {
+		if got := fuzzyMatch(tt.query, tt.text); got != tt.want {
+			t.Errorf("fuzzyMatch(%q, %q) = %v, want %v", tt.query, tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestFilterItems(t *testing.T) {
+	items := []PickerItem{
+		{Label: Label{Name: "bug", Description: "Something isn't working"}},
+		{Label: Label{Name: "docs", Description: "Improvements to documentation"}},
+		{Label: Label{Name: "type: feature"}, Current: Label{Name: "enhancement"}, Rename: true},
+		{Label: Label{Name: "question"}},
+	}
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"", "[0 1 2 3]"},
+		{"docs", "[1]"},
+		{"work", "[0]"},    // Description
+		{"enhance", "[2]"}, // Old name of a rename
+		{"quest", "[3]"},
+		{"zzz", "[]"},
+	}
+
+	for _, tt := range tests {
+		if got := fmt.Sprint(filterItems(items, tt.query)); got != tt.want {
+			t.Errorf("filterItems(%q) = %s, want %s", tt.query, got, tt.want)
+		}
+	}
+}
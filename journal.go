@@ -0,0 +1,341 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+)
+
+// Journal entry statuses
+const (
+	statusPending = "pending"
+	statusDone    = "done"
+	statusFailed  = "failed"
+)
+
+// Journal records the operations of an apply and how far it got
+type Journal struct {
+	Repo     string         `json:"repo"`
+	Started  time.Time      `json:"started"`
+	Snapshot string         `json:"snapshot,omitempty"`
+	Entries  []JournalEntry `json:"operations"`
+
+	path string
+}
+
+// JournalEntry is one operation and its status
+type JournalEntry struct {
+	Operation
+	Status string `json:"status"` // pending, done or failed
+	Error  string `json:"error,omitempty"`
+}
+
+// Starts a journal for the operations about to be applied to a repo
+func newJournal(repo, snapshotPath string, ops []Operation) (*Journal, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return nil, err
+	}
+	dir = filepath.Join(dir, "journals")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	journal := &Journal{Repo: repo, Started: time.Now().UTC(), Snapshot: snapshotPath}
+	for _, op := range ops {
+		journal.Entries = append(journal.Entries, JournalEntry{Operation: op, Status: statusPending})
+	}
+
+	name := strings.NewReplacer("/", "_", ":", "_").Replace(repo)
+	journal.path = filepath.Join(dir, name+"-"+journal.Started.Format("20060102T150405.000Z")+".json")
+	return journal, journal.save()
+}
+
+// Writes the journal to disk, replacing the previous version in one step
+// so an interrupted write never leaves a truncated file
+func (j *Journal) save() error {
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := j.path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, j.path)
+}
+
+// Loads a journal to resume it
+func loadJournal(path string) (*Journal, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read journal: %v", err)
+	}
+
+	journal := &Journal{path: path}
+	if err := json.Unmarshal(data, journal); err != nil {
+		return nil, fmt.Errorf("failed to parse journal %s: %v", path, err)
+	}
+	if !isValidRepo(journal.Repo) {
+		return nil, fmt.Errorf("journal %s has an invalid repo: %q", path, journal.Repo)
+	}
+	return journal, nil
+}
+
+// Returns the entries that haven't completed
+func (j *Journal) remaining() []JournalEntry {
+	var entries []JournalEntry
+	for _, entry := range j.Entries {
+		if entry.Status != statusDone {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// Returns the indexes of the entries left to run, grouped into runs of
+// the same action. Every phase finishes before the next starts, so deletes
+// still happen before creates and merges find their targets.
+func (j *Journal) phases() [][]int {
+	var phases [][]int
+	for i, entry := range j.Entries {
+		if entry.Status == statusDone {
+			continue
+		}
+		last := len(phases) - 1
+		if last < 0 || j.Entries[phases[last][0]].Action != entry.Action {
+			phases = append(phases, nil)
+			last++
+		}
+		phases[last] = append(phases[last], i)
+	}
+	return phases
+}
+
+// Returns whether any operation hasn't been tried yet
+func (j *Journal) hasPending() bool {
+	for _, entry := range j.Entries {
+		if entry.Status == statusPending {
+			return true
+		}
+	}
+	return false
+}
+
+// Runs every operation in the journal that hasn't completed, recording
+// each result as it goes. Up to --concurrency operations of a phase run at
+// once, and their output is printed in order. Stops at the first failure
+// unless --keep-going is set. On Ctrl+C the running operations finish and
+// the rest are left for gabel resume.
+func runJournal(out io.Writer, journal *Journal) error {
+	interrupted, stop := watchInterrupt()
+	defer stop()
+
+	var (
+		mu      sync.Mutex // Guards the journal, failed and stopErr
+		failed  int
+		stopErr error
+	)
+	for _, phase := range journal.phases() {
+		output := newOrderedOutput(out, len(phase))
+		forEachParallel(len(phase), concurrency, func(k int) {
+			i := phase[k]
+			var buf bytes.Buffer
+			defer func() { output.done(k, buf.Bytes()) }()
+
+			mu.Lock()
+			halted := stopErr != nil || interrupted()
+			mu.Unlock()
+			if halted {
+				return
+			}
+
+			err := applyOperation(&buf, journal.Repo, journal.Entries[i].Operation, i+1, len(journal.Entries))
+
+			mu.Lock()
+			defer mu.Unlock()
+			entry := &journal.Entries[i]
+			entry.Status, entry.Error = statusDone, ""
+			if err != nil {
+				entry.Status, entry.Error = statusFailed, err.Error()
+			}
+			if saveErr := journal.save(); saveErr != nil {
+				if stopErr == nil {
+					stopErr = fmt.Errorf("failed to update journal %s: %v", journal.path, saveErr)
+				}
+				return
+			}
+
+			if err != nil {
+				if !keepGoing {
+					if stopErr == nil {
+						stopErr = fmt.Errorf("%v\nResume with: gabel resume %s", err, journal.path)
+					}
+					return
+				}
+				fmt.Fprintf(&buf, "      failed: %v\n", err)
+				failed++
+			}
+		})
+
+		if stopErr != nil {
+			return stopErr
+		}
+		if interrupted() && journal.hasPending() {
+			return fmt.Errorf("interrupted with %d operations left\nResume with: gabel resume %s", len(journal.remaining()), journal.path)
+		}
+	}
+
+	if failed > 0 {
+		printFailedOperations(out, journal)
+		return fmt.Errorf("%d of %d operations failed\nRetry them with: gabel resume %s", failed, len(journal.Entries), journal.path)
+	}
+	return nil
+}
+
+// Writes the output of concurrent operations in their original order,
+// each as soon as every operation before it has finished
+type orderedOutput struct {
+	mu     sync.Mutex
+	out    io.Writer
+	chunks [][]byte
+	ready  []bool
+	next   int
+}
+
+func newOrderedOutput(out io.Writer, n int) *orderedOutput {
+	return &orderedOutput{out: out, chunks: make([][]byte, n), ready: make([]bool, n)}
+}
+
+// Records the output of operation k and flushes whatever is now in order
+func (o *orderedOutput) done(k int, data []byte) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.chunks[k], o.ready[k] = data, true
+	for o.next < len(o.ready) && o.ready[o.next] {
+		_, _ = o.out.Write(o.chunks[o.next])
+		o.chunks[o.next] = nil
+		o.next++
+	}
+}
+
+// Lists the operations that failed, with their errors
+func printFailedOperations(out io.Writer, journal *Journal) {
+	fmt.Fprintf(out, "\nFailed operations:\n")
+	for i, entry := range journal.Entries {
+		if entry.Status == statusFailed {
+			fmt.Fprintf(out, "  [%d/%d] %s %s: %s\n", i+1, len(journal.Entries), entry.Action, entry.Label.Name, firstLine(entry.Error))
+		}
+	}
+}
+
+// Ctrl+C handling shared by every apply in progress
+var interrupts struct {
+	sync.Mutex
+	watchers int
+	signals  chan os.Signal
+	flag     atomic.Bool
+}
+
+// Catches Ctrl+C while operations run. The first one asks the running
+// applies to stop after their current operation; a second quits at once.
+// The returned stop function restores the default handling.
+func watchInterrupt() (interrupted func() bool, stop func()) {
+	interrupts.Lock()
+	defer interrupts.Unlock()
+
+	if interrupts.watchers == 0 {
+		interrupts.flag.Store(false)
+		signals := make(chan os.Signal, 2)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		interrupts.signals = signals
+		go func() {
+			for range signals {
+				if interrupts.flag.Swap(true) {
+					os.Exit(exitError)
+				}
+				fmt.Fprintln(os.Stderr, "\nStopping after the current operation. Press Ctrl+C again to quit now.")
+			}
+		}()
+	}
+	interrupts.watchers++
+
+	var once sync.Once
+	stop = func() {
+		once.Do(func() {
+			interrupts.Lock()
+			defer interrupts.Unlock()
+			interrupts.watchers--
+			if interrupts.watchers == 0 {
+				signal.Stop(interrupts.signals)
+				close(interrupts.signals)
+			}
+		})
+	}
+	return interrupts.flag.Load, stop
+}
+
+// Retries the operations of an interrupted or failed apply
+func runResume(cmd *cobra.Command, args []string) {
+	InitLogger(debug)
+
+	journal, err := loadJournal(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	remaining := journal.remaining()
+	if len(remaining) == 0 {
+		fmt.Printf("Every operation in %s has completed. Nothing to do.\n", args[0])
+		os.Exit(exitNoChanges)
+	}
+
+	if err := initStore(journal.Repo); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(exitError)
+	}
+
+	fmt.Printf("%d of %d operations on %s are left:\n\n", len(remaining), len(journal.Entries), journal.Repo)
+	for _, entry := range remaining {
+		fmt.Printf("  %s\n", describeOperation(journal.Repo, entry.Operation))
+		if entry.Error != "" {
+			fmt.Printf("      last error: %s\n", firstLine(entry.Error))
+		}
+	}
+	fmt.Println()
+
+	if !yes {
+		prompt := promptui.Prompt{
+			Label:     "Resume",
+			IsConfirm: true,
+			Default:   "n",
+		}
+		if _, err := prompt.Run(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: cancelled\n")
+			os.Exit(exitError)
+		}
+	}
+
+	if err := runJournal(os.Stdout, journal); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	fmt.Printf("\nDone! Finished the %d remaining operations.\n", len(remaining))
+	os.Exit(exitChanged)
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestJournalStopsAndResumes(t *testing.T) {
+	ms := useMemStore(t)
+	ms.labels["owner/repo"] = []Label{{Name: "stale", Color: "ffffff"}}
+	ms.failOn["enhancement"] = errors.New("HTTP 502")
+
+	// One at a time, so the create after the failure never starts
+	oldConcurrency := concurrency
+	defer func() { concurrency = oldConcurrency }()
+	concurrency = 1
+
+	summary := ActionSummary{
+		ToCreate: []Label{{Name: "enhancement", Color: "a2eeef"}, {Name: "docs", Color: "0075ca"}},
+		ToDelete: []Label{{Name: "stale", Color: "ffffff"}},
+	}
+
+	var out strings.Builder
+	err := applyChangesTo(&out, summary, "owner/repo")
+	if err == nil || !strings.Contains(err.Error(), "gabel resume ") {
+		t.Fatalf("Expected a failure pointing at gabel resume, got %v", err)
+	}
+
+	path := err.Error()[strings.Index(err.Error(), "gabel resume ")+len("gabel resume "):]
+	journal, err := loadJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var statuses []string
+	for _, entry := range journal.Entries {
+		statuses = append(statuses, entry.Action+" "+entry.Label.Name+" "+entry.Status)
+	}
+	want := "delete stale done, create enhancement failed, create docs pending"
+	if strings.Join(statuses, ", ") != want {
+		t.Errorf("Journal = %v, want %s", statuses, want)
+	}
+
+	// Resuming retries only what didn't complete
+	delete(ms.failOn, "enhancement")
+	out.Reset()
+	if err := runJournal(&out, journal); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out.String(), "Deleting stale") {
+		t.Errorf("Completed operations should not run again:\n%s", out.String())
+	}
+	if len(ms.labels["owner/repo"]) != 2 {
+		t.Errorf("Labels after resume = %+v", ms.labels["owner/repo"])
+	}
+
+	reloaded, err := loadJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reloaded.remaining()) != 0 {
+		t.Errorf("Every operation should be done, got %+v", reloaded.remaining())
+	}
+}
+
+func TestJournalKeepGoing(t *testing.T) {
+	ms := useMemStore(t)
+	ms.labels["owner/repo"] = []Label{}
+	ms.failOn["enhancement"] = errors.New("HTTP 502")
+
+	keepGoing = true
+	t.Cleanup(func() { keepGoing = false })
+
+	summary := ActionSummary{
+		ToCreate: []Label{{Name: "enhancement", Color: "a2eeef"}, {Name: "docs", Color: "0075ca"}},
+	}
+
+	var out strings.Builder
+	err := applyChangesTo(&out, summary, "owner/repo")
+	if err == nil || !strings.Contains(err.Error(), "1 of 2 operations failed") {
+		t.Fatalf("Expected a failure count, got %v", err)
+	}
+	if indexOfLabel(ms.labels["owner/repo"], "docs") < 0 {
+		t.Error("Operations after the failure should still run")
+	}
+	if !strings.Contains(out.String(), "Failed operations:\n  [1/2] create enhancement: ") {
+		t.Errorf("Expected a failed-operations report, got:\n%s", out.String())
+	}
+}
+
+func TestWatchInterrupt(t *testing.T) {
+	interrupted, stop := watchInterrupt()
+	_, stopOther := watchInterrupt()
+
+	interrupts.flag.Store(true)
+	if !interrupted() {
+		t.Error("interrupted() should report the flag")
+	}
+
+	stop()
+	stop()
+	if interrupts.watchers != 1 {
+		t.Errorf("stop should only count once, got %d watchers", interrupts.watchers)
+	}
+	stopOther()
+
+	// The next apply starts without the old interrupt
+	interrupted, stop = watchInterrupt()
+	defer stop()
+	if interrupted() {
+		t.Error("A new watcher should start uninterrupted")
+	}
+}
+
+func TestJournalConcurrentOutputIsOrdered(t *testing.T) {
+	ms := useMemStore(t)
+	ms.labels["owner/repo"] = []Label{{Name: "stale", Color: "ffffff"}}
+
+	oldConcurrency := concurrency
+	defer func() { concurrency = oldConcurrency }()
+	concurrency = 8
+
+	summary := ActionSummary{ToDelete: []Label{{Name: "stale", Color: "ffffff"}}}
+	for i := 1; i <= 20; i++ {
+		summary.ToCreate = append(summary.ToCreate, Label{Name: fmt.Sprintf("label-%02d", i), Color: "ededed"})
+	}
+
+	var out strings.Builder
+	if err := applyChangesTo(&out, summary, "owner/repo"); err != nil {
+		t.Fatal(err)
+	}
+
+	var want strings.Builder
+	fmt.Fprintf(&want, "[1/21] Deleting stale...\n")
+	for i := 1; i <= 20; i++ {
+		fmt.Fprintf(&want, "[%d/21] Creating label-%02d...\n", i+1, i)
+	}
+	if !strings.Contains(out.String(), want.String()) {
+		t.Errorf("Output out of order:\n%s", out.String())
+	}
+	if len(ms.labels["owner/repo"]) != 20 {
+		t.Errorf("Expected 20 labels, got %d", len(ms.labels["owner/repo"]))
+	}
+}
+
+func TestJournalPhases(t *testing.T) {
+	journal := &Journal{Entries: []JournalEntry{
+		{Operation: Operation{Action: "delete"}, Status: statusDone},
+		{Operation: Operation{Action: "delete"}, Status: statusFailed},
+		{Operation: Operation{Action: "update"}, Status: statusPending},
+		{Operation: Operation{Action: "create"}, Status: statusPending},
+		{Operation: Operation{Action: "create"}, Status: statusPending},
+	}}
+
+	got := fmt.Sprint(journal.phases())
+	if got != "[[1] [2] [3 4]]" {
+		t.Errorf("phases() = %s, want [[1] [2] [3 4]]", got)
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"io"
+	"unicode/utf8"
+)
+
+// keyKind is the kind of key a keyEvent reports
+type keyKind int
+
+const (
+	keyRune keyKind = iota // A printable character, in Rune
+	keyEnter
+	keyTab
+	keyBackspace
+	keyEsc
+	keyCtrlC
+	keyUp
+	keyDown
+	keyLeft
+	keyRight
+	keyPgUp
+	keyPgDn
+	keyHome
+	keyEnd
+	keyUnknown // A control character or escape sequence gabel doesn't use
+)
+
+// keyEvent is one decoded keypress
+type keyEvent struct {
+	Kind keyKind
+	Rune rune
+}
+
+// keyDecoder turns raw terminal input into key events. A character split
+// across two reads is held until the rest of it arrives.
+type keyDecoder struct {
+	rest []byte
+}
+
+// Decodes the bytes of one read. Terminals send an escape sequence in a
+// single write, so an Esc at the end of a read is the Esc key itself.
+func (d *keyDecoder) decode(data []byte) []keyEvent {
+	data = append(d.rest, data...)
+	d.rest = nil
+
+	var events []keyEvent
+	for len(data) > 0 {
+		b := data[0]
+		switch {
+		case b == 0x1b:
+			event, n := decodeEscape(data)
+			events = append(events, event)
+			data = data[n:]
+			continue
+		case b == '\r' || b == '\n':
+			events = append(events, keyEvent{Kind: keyEnter})
+		case b == '\t':
+			events = append(events, keyEvent{Kind: keyTab})
+		case b == 127 || b == '\b':
+			events = append(events, keyEvent{Kind: keyBackspace})
+		case b == 3:
+			events = append(events, keyEvent{Kind: keyCtrlC})
+		case b < ' ':
+			events = append(events, keyEvent{Kind: keyUnknown})
+		default:
+			if !utf8.FullRune(data) {
+				d.rest = append([]byte{}, data...)
+				return events
+			}
+			r, n := utf8.DecodeRune(data)
+			events = append(events, keyEvent{Kind: keyRune, Rune: r})
+			data = data[n:]
+			continue
+		}
+		data = data[1:]
+	}
+	return events
+}
+
+// Decodes the escape sequence at the start of data, such as ESC [ A for
+// Up or ESC [ 5 ~ for PgUp, and returns how many bytes it used
+func decodeEscape(data []byte) (keyEvent, int) {
+	if len(data) < 3 || (data[1] != '[' && data[1] != 'O') {
+		return keyEvent{Kind: keyEsc}, 1
+	}
+
+	// Parameters run until a final byte between @ and ~
+	end := 2
+	for end < len(data) && (data[end] < 0x40 || data[end] > 0x7e) {
+		end++
+	}
+	if end == len(data) {
+		return keyEvent{Kind: keyEsc}, 1
+	}
+
+	kind := keyUnknown
+	switch data[end] {
+	case 'A':
+		kind = keyUp
+	case 'B':
+		kind = keyDown
+	case 'C':
+		kind = keyRight
+	case 'D':
+		kind = keyLeft
+	case 'H':
+		kind = keyHome
+	case 'F':
+		kind = keyEnd
+	case '~':
+		switch string(data[2:end]) {
+		case "1", "7":
+			kind = keyHome
+		case "4", "8":
+			kind = keyEnd
+		case "5":
+			kind = keyPgUp
+		case "6":
+			kind = keyPgDn
+		}
+	}
+	return keyEvent{Kind: kind}, end + 1
+}
+
+// keyReader reads key events in the background. It only reads when asked
+// to, so nothing typed after the picker closes is taken from the prompts
+// that follow it.
+type keyReader struct {
+	requests chan struct{}
+	events   chan []keyEvent
+}
+
+func newKeyReader(r io.Reader) *keyReader {
+	reader := &keyReader{requests: make(chan struct{}), events: make(chan []keyEvent)}
+	go func() {
+		var decoder keyDecoder
+		buf := make([]byte, 256)
+		for range reader.requests {
+			n, err := r.Read(buf)
+			if err != nil {
+				// Input is gone, so treat it like Ctrl+C
+				reader.events <- []keyEvent{{Kind: keyCtrlC}}
+				continue
+			}
+			reader.events <- decoder.decode(buf[:n])
+		}
+	}()
+	return reader
+}
+
+// Asks for the next read; its events arrive on events
+func (r *keyReader) request() {
+	r.requests <- struct{}{}
+}
+
+// Stops the reader once no read is outstanding
+func (r *keyReader) close() {
+	close(r.requests)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestKeyDecoder(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []keyEvent
+	}{
+		{"letters", "ab", []keyEvent{{Kind: keyRune, Rune: 'a'}, {Kind: keyRune, Rune: 'b'}}},
+		{"utf-8", "é✓", []keyEvent{{Kind: keyRune, Rune: 'é'}, {Kind: keyRune, Rune: '✓'}}},
+		{"enter", "\r", []keyEvent{{Kind: keyEnter}}},
+		{"tab", "\t", []keyEvent{{Kind: keyTab}}},
+		{"backspace", "\x7f", []keyEvent{{Kind: keyBackspace}}},
+		{"ctrl+c", "\x03", []keyEvent{{Kind: keyCtrlC}}},
+		{"esc alone", "\x1b", []keyEvent{{Kind: keyEsc}}},
+		{"arrows", "\x1b[A\x1b[B", []keyEvent{{Kind: keyUp}, {Kind: keyDown}}},
+		{"application arrows", "\x1bOA", []keyEvent{{Kind: keyUp}}},
+		{"page keys", "\x1b[5~\x1b[6~", []keyEvent{{Kind: keyPgUp}, {Kind: keyPgDn}}},
+		{"home and end", "\x1b[H\x1b[F\x1b[1~\x1b[4~\x1bOH", []keyEvent{{Kind: keyHome}, {Kind: keyEnd}, {Kind: keyHome}, {Kind: keyEnd}, {Kind: keyHome}}},
+		{"modified arrow", "\x1b[1;5A", []keyEvent{{Kind: keyUp}}},
+		{"unknown sequence", "\x1b[3~", []keyEvent{{Kind: keyUnknown}}},
+		{"esc then letter", "\x1bq", []keyEvent{{Kind: keyEsc}, {Kind: keyRune, Rune: 'q'}}},
+	}
+
+	for _, tt := range tests {
+		var decoder keyDecoder
+		if got := decoder.decode([]byte(tt.input)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: decode(%q) = %v, want %v", tt.name, tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestKeyDecoderSplitRune(t *testing.T) {
+	var decoder keyDecoder
+	data := []byte("✓")
+
+	if got := decoder.decode(data[:1]); len(got) != 0 {
+		t.Errorf("Half a character should wait for the rest, got %v", got)
+	}
+	got := decoder.decode(data[1:])
+	if len(got) != 1 || got[0].Rune != '✓' {
+		t.Errorf("Expected ✓ once the rest arrived, got %v", got)
+	}
+}
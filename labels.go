@@ -1,24 +1,43 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/url"
-	"os"
 	"os/exec"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 )
 
-// Verifies gh CLI is installed and authenticated
-func CheckGitHubCLI() error {
+var ghStatusRegex = regexp.MustCompile(`\(HTTP (\d{3})\)`)
+
+// Verifies gh CLI is installed and authenticated, with each host if given
+func CheckGitHubCLI(hosts ...string) error {
 	_, err := exec.LookPath("gh")
 	if err != nil {
 		return fmt.Errorf("GitHub CLI (gh) is required but not found.\nInstall it from: https://cli.github.com")
 	}
 
-	cmd := exec.Command("gh", "auth", "status")
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("not authenticated with GitHub.\nRun: gh auth login")
+	if len(hosts) == 0 {
+		cmd := exec.Command("gh", "auth", "status")
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("not authenticated with GitHub.\nRun: gh auth login")
+		}
+	}
+
+	for _, host := range hosts {
+		cmd := exec.Command("gh", "auth", "status", "--hostname", host)
+		if err := cmd.Run(); err != nil {
+			if isGitHubDotCom(host) {
+				return fmt.Errorf("not authenticated with GitHub.\nRun: gh auth login")
+			}
+			return fmt.Errorf("not authenticated with %s.\nRun: gh auth login --hostname %s", host, host)
+		}
 	}
 
 	return nil
@@ -28,28 +47,17 @@ func CheckGitHubCLI() error {
 func FetchLabels(repo string) ([]Label, error) {
 	LogDebug("Fetching labels from %s", repo)
 
-	cmd := exec.Command("gh", "api", fmt.Sprintf("repos/%s/labels", repo), "--paginate")
-	output, err := cmd.Output()
+	labels, err := store.FetchLabels(repo)
 	if err != nil {
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			stderr := string(exitErr.Stderr)
-			if strings.Contains(stderr, "HTTP 404") {
-				return nil, fmt.Errorf("repository not found: %s", repo)
-			}
-			if strings.Contains(stderr, "HTTP 403") {
-				if strings.Contains(stderr, "rate limit") {
-					return nil, fmt.Errorf("GitHub API rate limit exceeded. Please wait and try again")
-				}
-				return nil, fmt.Errorf("access denied. You may not have permission to view %s", repo)
-			}
-			return nil, fmt.Errorf("GitHub API error: %s", stderr)
+		switch {
+		case errors.Is(err, ErrNotFound):
+			return nil, fmt.Errorf("repository not found: %s: %w", repo, err)
+		case errors.Is(err, ErrRateLimited):
+			return nil, fmt.Errorf("GitHub API rate limit exceeded. Please wait and try again: %w", err)
+		case errors.Is(err, ErrForbidden):
+			return nil, fmt.Errorf("access denied. You may not have permission to view %s: %w", repo, err)
 		}
-		return nil, err
-	}
-
-	var labels []Label
-	if err := json.Unmarshal(output, &labels); err != nil {
-		return nil, fmt.Errorf("failed to parse labels: %v", err)
+		return nil, fmt.Errorf("GitHub API error: %w", err)
 	}
 
 	LogDebug("Fetched %d labels from %s", len(labels), repo)
@@ -58,49 +66,249 @@ func FetchLabels(repo string) ([]Label, error) {
 
 func CreateLabel(repo string, label Label) error {
 	LogDebug("Creating label '%s' in %s", label.Name, repo)
-	
+
 	// Validate before creation
 	if err := validateLabel(label); err != nil {
 		return err
 	}
-	
-	color, _ := validateColor(label.Color) // Already validated above
-
-	args := []string{
-		"api",
-		fmt.Sprintf("repos/%s/labels", repo),
-		"-f", fmt.Sprintf("name=%s", label.Name),
-		"-f", fmt.Sprintf("color=%s", color),
-		"-f", fmt.Sprintf("description=%s", label.Description),
-	}
 
-	cmd := exec.Command("gh", args...)
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+	label.Color, _ = validateColor(label.Color) // Already validated above
+	return store.CreateLabel(repo, label)
 }
 
 func UpdateLabel(repo string, label Label) error {
 	LogDebug("Updating label '%s' in %s", label.Name, repo)
 
+	// Validate before update
+	if err := validateLabel(label); err != nil {
+		return err
+	}
+
+	label.Color, _ = validateColor(label.Color) // Already validated above
+	return store.UpdateLabel(repo, label.Name, label)
+}
+
+// Renames a label in place, so issues and PRs keep it
+func RenameLabel(repo string, from string, label Label) error {
+	LogDebug("Renaming label '%s' to '%s' in %s", from, label.Name, repo)
+
+	// Validate before rename
+	if err := validateLabel(label); err != nil {
+		return err
+	}
+
+	label.Color, _ = validateColor(label.Color) // Already validated above
+	return store.UpdateLabel(repo, from, label)
+}
+
+func DeleteLabel(repo string, labelName string) error {
+	LogDebug("Deleting label '%s' from %s", labelName, repo)
+	return store.DeleteLabel(repo, labelName)
+}
+
+// Lists the open and closed issues and PRs that have a label
+func ListLabeledIssues(repo string, label string) ([]Issue, error) {
+	LogDebug("Listing issues labeled '%s' in %s", label, repo)
+	return store.ListLabeledIssues(repo, label)
+}
+
+func AddIssueLabel(repo string, number int, label string) error {
+	LogDebug("Adding label '%s' to #%d in %s", label, number, repo)
+	return store.AddIssueLabels(repo, number, []string{label})
+}
+
+func RemoveIssueLabel(repo string, number int, label string) error {
+	LogDebug("Removing label '%s' from #%d in %s", label, number, repo)
+	return store.RemoveIssueLabel(repo, number, label)
+}
+
+// Counts the issues and PRs, open and closed, that carry each label
+func LabelUsage(repo string, names []string) (map[string]LabelUses, error) {
+	LogDebug("Counting usage of %d labels in %s", len(names), repo)
+
+	usage := make(map[string]LabelUses)
+	for start := 0; start < len(names); start += usageBatchSize {
+		end := start + usageBatchSize
+		if end > len(names) {
+			end = len(names)
+		}
+
+		counts, err := store.LabelUsage(repo, names[start:end])
+		if err != nil {
+			return nil, err
+		}
+		for name, count := range counts {
+			usage[name] = count
+		}
+	}
+	return usage, nil
+}
+
+// ghStore runs every GitHub API call through the gh CLI
+type ghStore struct {
+	host string // Empty for gh's default host
+}
+
+func (s ghStore) FetchLabels(repo string) ([]Label, error) {
+	output, err := s.api(fmt.Sprintf("repos/%s/labels?per_page=100", repo), "--paginate")
+	if err != nil {
+		return nil, err
+	}
+
+	labels, err := decodePages[Label](bytes.NewReader(output))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse labels: %v", err)
+	}
+	return labels, nil
+}
+
+func (s ghStore) CreateLabel(repo string, label Label) error {
+	_, err := s.api(
+		fmt.Sprintf("repos/%s/labels", repo),
+		"-f", fmt.Sprintf("name=%s", label.Name),
+		"-f", fmt.Sprintf("color=%s", strings.TrimPrefix(label.Color, "#")),
+		"-f", fmt.Sprintf("description=%s", label.Description))
+	return err
+}
+
+func (s ghStore) UpdateLabel(repo string, name string, label Label) error {
 	args := []string{
-		"api",
-		fmt.Sprintf("repos/%s/labels/%s", repo, url.PathEscape(label.Name)),
+		fmt.Sprintf("repos/%s/labels/%s", repo, url.PathEscape(name)),
 		"--method", "PATCH",
 		"-f", fmt.Sprintf("color=%s", strings.TrimPrefix(label.Color, "#")),
 		"-f", fmt.Sprintf("description=%s", label.Description),
 	}
+	if label.Name != name {
+		args = append(args, "-f", fmt.Sprintf("new_name=%s", label.Name))
+	}
 
-	cmd := exec.Command("gh", args...)
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+	_, err := s.api(args...)
+	return err
 }
 
-func DeleteLabel(repo string, labelName string) error {
-	LogDebug("Deleting label '%s' from %s", labelName, repo)
+func (s ghStore) DeleteLabel(repo string, name string) error {
+	_, err := s.api(
+		fmt.Sprintf("repos/%s/labels/%s", repo, url.PathEscape(name)),
+		"--method", "DELETE")
+	return err
+}
+
+func (s ghStore) ListOrgRepos(org string) ([]Repo, error) {
+	output, err := s.api(fmt.Sprintf("orgs/%s/repos?per_page=100&type=all", url.PathEscape(org)), "--paginate")
+	if err != nil {
+		return nil, err
+	}
+
+	repos, err := decodePages[Repo](bytes.NewReader(output))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse repos: %v", err)
+	}
+	return repos, nil
+}
+
+func (s ghStore) LabelUsage(repo string, names []string) (map[string]LabelUses, error) {
+	query, variables, err := usageQuery(repo, names)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := s.graphql(query, variables)
+	if err != nil {
+		return nil, err
+	}
+	return decodeUsage(output, names)
+}
+
+func (s ghStore) ListLabeledIssues(repo string, label string) ([]Issue, error) {
+	return listLabeledIssues(repo, label, s.graphql)
+}
 
-	cmd := exec.Command("gh", "api",
-		fmt.Sprintf("repos/%s/labels/%s", repo, url.PathEscape(labelName)),
+// Sends a GraphQL query through gh api graphql
+func (s ghStore) graphql(query string, variables map[string]string) ([]byte, error) {
+	args := []string{"graphql", "-f", "query=" + query}
+	for name, value := range variables {
+		args = append(args, "-f", fmt.Sprintf("%s=%s", name, value))
+	}
+	return s.api(args...)
+}
+
+func (s ghStore) AddIssueLabels(repo string, number int, labels []string) error {
+	args := []string{fmt.Sprintf("repos/%s/issues/%d/labels", repo, number)}
+	for _, label := range labels {
+		args = append(args, "-f", fmt.Sprintf("labels[]=%s", label))
+	}
+
+	_, err := s.api(args...)
+	return err
+}
+
+func (s ghStore) RemoveIssueLabel(repo string, number int, label string) error {
+	_, err := s.api(
+		fmt.Sprintf("repos/%s/issues/%d/labels/%s", repo, number, url.PathEscape(label)),
 		"--method", "DELETE")
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
-}
\ No newline at end of file
+	return err
+}
+
+// Runs gh api against the store's host, backing off when rate limited
+func (s ghStore) api(args ...string) ([]byte, error) {
+	if s.host != "" {
+		args = append([]string{"--hostname", s.host}, args...)
+	}
+
+	for attempt := 0; ; attempt++ {
+		output, err := runGH(append([]string{"api"}, args...)...)
+		if err == nil || !errors.Is(err, ErrRateLimited) || attempt >= maxRetries {
+			return output, err
+		}
+
+		// gh doesn't show response headers, so fall back to exponential backoff
+		delay := backoffDelay(attempt)
+		LogDebug("Rate limited, retrying in %s", delay)
+		time.Sleep(delay)
+	}
+}
+
+// Decodes gh api --paginate output, which is one JSON array per page
+// written back to back, into a single list
+func decodePages[T any](r io.Reader) ([]T, error) {
+	items := []T{}
+	dec := json.NewDecoder(r)
+	for page := 1; ; page++ {
+		var pageItems []T
+		if err := dec.Decode(&pageItems); err == io.EOF {
+			return items, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("page %d: %v", page, err)
+		}
+		items = append(items, pageItems...)
+	}
+}
+
+// Runs gh and returns its output, turning HTTP failures into APIErrors
+func runGH(args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("gh", args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if _, ok := err.(*exec.ExitError); ok {
+			return nil, ghAPIError(stdout.Bytes(), stderr.Bytes())
+		}
+		return nil, err
+	}
+	return stdout.Bytes(), nil
+}
+
+// Builds an error from a failed gh api call. On HTTP errors gh prints
+// GitHub's JSON error body to stdout and "(HTTP 404)" to stderr.
+func ghAPIError(stdout, stderr []byte) error {
+	match := ghStatusRegex.FindSubmatch(stderr)
+	if match == nil {
+		return fmt.Errorf("%s", strings.TrimSpace(string(stderr)))
+	}
+
+	status, _ := strconv.Atoi(string(match[1]))
+	rateLimited := bytes.Contains(bytes.ToLower(stderr), []byte("rate limit"))
+	return newAPIError(status, stdout, rateLimited)
+}
@@ -1,8 +1,12 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
 	"os"
 	"os/exec"
+	"strings"
 	"testing"
 )
 
@@ -67,4 +71,30 @@ func TestErrorMessages(t *testing.T) {
 	} else if err.Error() != "GitHub CLI (gh) is required but not found.\nInstall it from: https://cli.github.com" {
 		t.Errorf("Unexpected error message: %v", err)
 	}
-}
\ No newline at end of file
+}
+func TestDecodePages(t *testing.T) {
+	for _, pages := range []int{1, 2, 10} {
+		t.Run(fmt.Sprintf("%d pages", pages), func(t *testing.T) {
+			// gh api --paginate writes each page's array back to back
+			var output bytes.Buffer
+			for _, page := range labelPages(pages) {
+				data, _ := json.Marshal(page)
+				output.Write(data)
+			}
+
+			labels, err := decodePages[Label](&output)
+			if err != nil {
+				t.Fatalf("decodePages() error = %v", err)
+			}
+
+			want := (pages-1)*100 + 37
+			if len(labels) != want {
+				t.Errorf("Expected %d labels, got %d", want, len(labels))
+			}
+		})
+	}
+
+	if _, err := decodePages[Label](strings.NewReader(`[{"name": "bug"}][{"name": `)); err == nil {
+		t.Error("Expected error for truncated output")
+	}
+}
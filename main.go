@@ -3,28 +3,168 @@ package main
 import (
 	"fmt"
 	"os"
+	"regexp"
 	"strings"
 
 	"github.com/spf13/cobra"
 )
 
 var (
-	verbose bool
-	debug   bool
+	verbose  bool
+	debug    bool
+	backend  string
+	hostname string
+	yes      bool
+	prune    bool
+	include  []string
+	exclude  []string
+
+	dryRun       bool
+	outputFormat string
+	savePlanPath string
+
+	exportFormat string
+	exportFile   string
+
+	destFile     string
+	org          string
+	topic        string
+	matchPattern string
+	parallel     int
+
+	deleteInUse    bool
+	snapshotIssues bool
+	keepGoing      bool
+	concurrency    int
+
+	ignoreExtra bool
+
+	protect  []string
+	noDelete bool
+
+	confirmThreshold int
+)
+
+// Exit codes for non-interactive runs
+const (
+	exitNoChanges = 0 // destination already matched
+	exitError     = 1
+	exitChanged   = 2 // labels were created, updated or deleted
 )
 
 var rootCmd = &cobra.Command{
-	Use:     "gabel source-repo dest-repo",
+	Use:     "gabel source dest-repo...",
 	Short:   "Safely copy GitHub labels between repositories",
-	Long:    "Gabel helps you copy GitHub labels from one repo to another with an interactive picker.",
+	Long:    "Gabel helps you copy GitHub labels from one repo to another with an interactive picker.\nThe source can be a repo or a local labels file (.yaml, .yml, .json, .toml, .csv or .md).\nSeveral comma-separated sources are combined, the first taking priority.",
 	Version: Version,
-	Args:    cobra.ExactArgs(2),
+	Args:    cobra.MinimumNArgs(1),
 	Run:     run,
 }
 
+var applyCmd = &cobra.Command{
+	Use:   "apply plan.json",
+	Short: "Apply a plan saved with --save-plan",
+	Long:  "Apply applies exactly the operations in a saved plan, without prompting. It fails if the destination has changed since the plan was made.",
+	Args:  cobra.ExactArgs(1),
+	Run:   runApply,
+}
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore snapshot.json|owner/repo",
+	Short: "Roll a repo back to a snapshot taken before an apply",
+	Long:  "Restore puts back the labels a repo had when a snapshot was taken, renaming renamed labels back and re-attaching deleted labels to their issues.\nGiven a repo instead of a file, it uses that repo's latest snapshot.",
+	Args:  cobra.ExactArgs(1),
+	Run:   runRestore,
+}
+
+var resumeCmd = &cobra.Command{
+	Use:   "resume journal.json",
+	Short: "Retry the operations a failed or interrupted apply didn't complete",
+	Long:  "Every apply records its operations in a journal. Resume runs the ones that failed or never ran, skipping those that completed.",
+	Args:  cobra.ExactArgs(1),
+	Run:   runResume,
+}
+
+var diffCmd = &cobra.Command{
+	Use:   "diff source dest-repo",
+	Short: "Show how a repo's labels differ from a source, without changing anything",
+	Long:  "Diff lists every label as only in the source, only in the destination, identical, or differing in color, description or case.\nIt exits 0 when the labels match, 1 when they differ and 2 on errors.",
+	Args:  cobra.ExactArgs(2),
+	Run:   runDiff,
+}
+
+var checkCmd = &cobra.Command{
+	Use:   "check source dest-repo...",
+	Short: "Report which repos have drifted from a source, without changing anything",
+	Long:  "Check compares each destination with the source and lists missing, changed and extra labels, and pending renames and merges.\nIt exits 0 when every repo matches, 1 when any has drifted and 2 on errors.",
+	Args:  cobra.MinimumNArgs(1),
+	Run:   runCheck,
+}
+
+var exportCmd = &cobra.Command{
+	Use:   "export owner/repo",
+	Short: "Write a repo's labels to a file",
+	Long:  "Export writes a repo's labels, sorted by name, as YAML, JSON, CSV or a Markdown table.\nThe output can be used as the source of a later gabel run.",
+	Args:  cobra.ExactArgs(1),
+	Run:   runExport,
+}
+
 func init() {
 	rootCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Show label descriptions")
-	rootCmd.Flags().BoolVarP(&debug, "debug", "d", false, "Show debug logs")
+	rootCmd.PersistentFlags().BoolVarP(&debug, "debug", "d", false, "Show debug logs")
+	rootCmd.PersistentFlags().StringVar(&backend, "backend", "api", "How to reach GitHub: api (built-in HTTP client) or gh (GitHub CLI)")
+	rootCmd.PersistentFlags().StringVar(&hostname, "hostname", defaultHostname(), "GitHub host for repos given as owner/repo")
+	rootCmd.Flags().BoolVarP(&yes, "yes", "y", false, "Apply without the picker or confirmation prompt")
+	rootCmd.Flags().StringSliceVar(&include, "include", nil, "Only copy labels matching these glob patterns")
+	rootCmd.Flags().StringSliceVar(&exclude, "exclude", nil, "Never touch labels matching these glob patterns")
+	rootCmd.Flags().BoolVar(&prune, "prune", false, "Delete destination labels that are not in the source")
+	rootCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show the plan without changing anything")
+	rootCmd.Flags().StringVarP(&outputFormat, "output", "o", "text", "Plan output format: text or json")
+	rootCmd.Flags().StringVar(&savePlanPath, "save-plan", "", "Save the plan to a file for 'gabel apply' (implies --dry-run)")
+	rootCmd.Flags().StringVar(&destFile, "dest-file", "", "Read destination repos from a file, one per line")
+	rootCmd.Flags().StringVar(&org, "org", "", "Use every repo in an organization as a destination")
+	rootCmd.Flags().StringVar(&topic, "topic", "", "With --org, only repos with this topic")
+	rootCmd.Flags().StringVar(&matchPattern, "match", "", "With --org, only repos whose name matches this regular expression")
+	rootCmd.Flags().IntVar(&parallel, "parallel", 4, "How many destination repos to sync at once")
+	rootCmd.Flags().BoolVar(&deleteInUse, "delete-in-use", false, "Let --yes and multi-repo runs delete labels that are still on issues or PRs")
+
+	rootCmd.Flags().BoolVar(&snapshotIssues, "snapshot-issues", true, "Record the issues of deleted and merged labels in the snapshot, so restore can re-attach them")
+
+	rootCmd.Flags().BoolVar(&keepGoing, "keep-going", false, "Carry on past failed operations and report them at the end")
+	rootCmd.Flags().IntVar(&concurrency, "concurrency", 4, "How many label operations to run at once in each repo")
+	rootCmd.Flags().StringSliceVar(&protect, "protect", nil, "Never change or delete destination labels matching these glob patterns")
+	rootCmd.Flags().BoolVar(&noDelete, "no-delete", false, "Only add and update labels; never delete destination labels")
+	rootCmd.Flags().IntVar(&confirmThreshold, "confirm-deletes", 1, "Type the repo name to confirm plans that delete at least this many labels (0 to only ask y/n)")
+
+	applyCmd.Flags().BoolVar(&keepGoing, "keep-going", false, "Carry on past failed operations and report them at the end")
+	applyCmd.Flags().IntVar(&concurrency, "concurrency", 4, "How many label operations to run at once")
+	applyCmd.Flags().StringSliceVar(&protect, "protect", nil, "Never change or delete destination labels matching these glob patterns")
+	applyCmd.Flags().BoolVar(&noDelete, "no-delete", false, "Refuse plans that delete destination labels")
+	rootCmd.AddCommand(applyCmd)
+
+	resumeCmd.Flags().BoolVarP(&yes, "yes", "y", false, "Resume without the confirmation prompt")
+	resumeCmd.Flags().BoolVar(&keepGoing, "keep-going", false, "Carry on past failed operations and report them at the end")
+	resumeCmd.Flags().IntVar(&concurrency, "concurrency", 4, "How many label operations to run at once")
+	rootCmd.AddCommand(resumeCmd)
+
+	restoreCmd.Flags().BoolVarP(&yes, "yes", "y", false, "Restore without the confirmation prompt")
+	restoreCmd.Flags().IntVar(&concurrency, "concurrency", 4, "How many label operations to run at once")
+	rootCmd.AddCommand(restoreCmd)
+
+	diffCmd.Flags().StringVarP(&outputFormat, "output", "o", "text", "Output format: text or json")
+	rootCmd.AddCommand(diffCmd)
+
+	checkCmd.Flags().StringVarP(&outputFormat, "output", "o", "text", "Report format: text, json or junit")
+	checkCmd.Flags().BoolVar(&ignoreExtra, "ignore-extra", false, "Don't count labels only the destination has as drift")
+	checkCmd.Flags().StringVar(&destFile, "dest-file", "", "Read destination repos from a file, one per line")
+	checkCmd.Flags().IntVar(&parallel, "parallel", 4, "How many repos to check at once")
+	checkCmd.Flags().StringSliceVar(&protect, "protect", nil, "Don't count destination labels matching these glob patterns as drift")
+	checkCmd.Flags().BoolVar(&noDelete, "no-delete", false, "Check for an additive sync: labels only the destination has aren't drift")
+	rootCmd.AddCommand(checkCmd)
+
+	exportCmd.Flags().StringVarP(&exportFormat, "format", "f", "yaml", "Output format: yaml, json, csv or markdown")
+	exportCmd.Flags().StringVar(&exportFile, "file", "", "Write to a file instead of stdout (format defaults to the file extension)")
+	rootCmd.AddCommand(exportCmd)
 }
 
 func main() {
@@ -36,62 +176,269 @@ func main() {
 
 func run(cmd *cobra.Command, args []string) {
 	sourceRepo := args[0]
-	destRepo := args[1]
 
 	InitLogger(debug)
 
-	if !isValidRepo(sourceRepo) || !isValidRepo(destRepo) {
-		fmt.Fprintf(os.Stderr, "Error: Invalid repo format. Use 'owner/repo' format.\n")
+	if outputFormat != "text" && outputFormat != "json" {
+		fmt.Fprintf(os.Stderr, "Error: Invalid output format %q. Use 'text' or 'json'.\n", outputFormat)
+		os.Exit(1)
+	}
+
+	var match *regexp.Regexp
+	if matchPattern != "" {
+		var err error
+		if match, err = regexp.Compile(matchPattern); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: Invalid --match pattern: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	dests, err := destinationArgs(args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if !isValidSource(sourceRepo) {
+		fmt.Fprintf(os.Stderr, "Error: Invalid repo format. Use 'owner/repo' or 'HOST/owner/repo' format.\n")
 		os.Exit(1)
 	}
+	for _, dest := range dests {
+		if !isValidRepo(dest) {
+			fmt.Fprintf(os.Stderr, "Error: Invalid repo format. Use 'owner/repo' or 'HOST/owner/repo' format.\n")
+			os.Exit(1)
+		}
+	}
 
-	if err := CheckGitHubCLI(); err != nil {
+	repos := append(append([]string{}, dests...), sourceRepos(sourceRepo)...)
+	if org != "" {
+		repos = append(repos, org)
+	}
+	if err := initStore(repos...); err != nil {
 		fmt.Fprintf(os.Stderr, "%v\n", err)
 		os.Exit(1)
 	}
 
+	if org != "" {
+		fmt.Fprintf(os.Stderr, "Listing repos in %s...\n", org)
+		orgDests, err := orgDestinations(org, topic, match)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		dests = append(dests, orgDests...)
+	}
+
+	dests = uniqueDestinations(dests, sourceRepo)
+	if len(dests) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: No destination repos. Pass them as arguments, with --dest-file or with --org.\n")
+		os.Exit(1)
+	}
+	if len(dests) > 1 && savePlanPath != "" {
+		fmt.Fprintf(os.Stderr, "Error: --save-plan works with a single destination repo.\n")
+		os.Exit(1)
+	}
+
 	LogDebug("Source repo: %s", sourceRepo)
-	LogDebug("Destination repo: %s", destRepo)
+	LogDebug("Destination repos: %s", strings.Join(dests, ", "))
 
-	fmt.Printf("Fetching labels from %s...\n", sourceRepo)
-	sourceLabels, err := FetchLabels(sourceRepo)
+	fmt.Fprintf(os.Stderr, "Fetching labels from %s...\n", sourceRepo)
+	source, err := loadSource(sourceRepo)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error fetching labels from %s: %v\n", sourceRepo, err)
 		os.Exit(1)
 	}
+	printSourceConflicts(os.Stderr, source.Conflicts)
+	protect = append(protect, source.Protected...)
+	noDelete = noDelete || source.Additive
 
-	if len(sourceLabels) == 0 {
+	if len(source.Labels) == 0 {
 		fmt.Fprintf(os.Stderr, "Error: No labels found in %s\n", sourceRepo)
 		os.Exit(1)
 	}
 
-	fmt.Printf("Fetching labels from %s...\n", destRepo)
+	if len(dests) > 1 {
+		os.Exit(runMulti(sourceRepo, source, dests))
+	}
+
+	destRepo := dests[0]
+	fmt.Fprintf(os.Stderr, "Fetching labels from %s...\n", destRepo)
 	destLabels, err := FetchLabels(destRepo)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error fetching labels from %s: %v\n", destRepo, err)
 		os.Exit(1)
 	}
 
-	LogDebug("Found %d labels in source, %d labels in destination", len(sourceLabels), len(destLabels))
+	LogDebug("Found %d labels in source, %d labels in destination", len(source.Labels), len(destLabels))
 
-	selectedLabels, err := ShowPicker(sourceLabels, destLabels, destRepo, verbose)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+	items := prepareItems(source, destLabels)
+
+	if !yes {
+		loadItemUsage(destRepo, items)
+		items, err = ShowPicker(items, destRepo, verbose)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Without a confirmation prompt, labels in use are only deleted on request
+	summary := summarize(items, destLabels)
+	if yes {
+		summary = guardInUse(destRepo, summary, os.Stderr)
 	}
 
-	if len(selectedLabels) == 0 {
+	if dryRun || savePlanPath != "" {
+		plan := newPlan(summary, sourceRepo, destRepo, destLabels)
+		if err := showPlan(plan); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if yes {
+		os.Exit(applyWithoutPrompt(summary, destRepo))
+	}
+
+	if len(selectedLabels(items)) == 0 {
 		fmt.Println("No labels selected. Nothing to do.")
 		return
 	}
 
-	if err := ConfirmAndApply(selectedLabels, destLabels, destRepo); err != nil {
+	if err := ConfirmAndApply(items, destLabels, destRepo); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 }
 
+// Applies the selection without prompting and returns the exit code
+func applyWithoutPrompt(summary ActionSummary, destRepo string) int {
+	if !hasChanges(summary) {
+		fmt.Printf("%s is up to date. Nothing to do.\n", destRepo)
+		return exitNoChanges
+	}
+
+	printActions(summary)
+	fmt.Println()
+
+	if err := applyChanges(summary, destRepo); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
+	}
+	return exitChanged
+}
+
+// Prints a dry-run plan and saves it when --save-plan is set
+func showPlan(plan Plan) error {
+	if outputFormat == "json" {
+		if err := writePlanJSON(os.Stdout, plan); err != nil {
+			return err
+		}
+	} else {
+		printPlan(os.Stdout, plan)
+	}
+
+	if savePlanPath != "" {
+		if err := savePlan(savePlanPath, plan); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Plan saved to %s. Run 'gabel apply %s' to apply it.\n", savePlanPath, savePlanPath)
+	}
+
+	return nil
+}
+
+// Applies a saved plan if the destination still matches it
+func runApply(cmd *cobra.Command, args []string) {
+	InitLogger(debug)
+
+	plan, err := loadPlan(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	summary, err := planSummary(plan)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid plan %s: %v\n", args[0], err)
+		os.Exit(exitError)
+	}
+
+	protect = append(protect, plan.Protected...)
+	noDelete = noDelete || plan.NoDelete
+	for _, check := range []func(ActionSummary) error{checkProtected, checkNoDelete} {
+		if err := check(summary); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid plan %s: %v\n", args[0], err)
+			os.Exit(exitError)
+		}
+	}
+
+	if err := initStore(plan.Dest); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(exitError)
+	}
+
+	fmt.Fprintf(os.Stderr, "Fetching labels from %s...\n", plan.Dest)
+	destLabels, err := FetchLabels(plan.Dest)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error fetching labels from %s: %v\n", plan.Dest, err)
+		os.Exit(exitError)
+	}
+
+	if labelsDigest(destLabels) != plan.DestDigest {
+		fmt.Fprintf(os.Stderr, "Error: %s has changed since the plan was made. Create a new plan.\n", plan.Dest)
+		os.Exit(exitError)
+	}
+
+	if !hasChanges(summary) {
+		fmt.Printf("%s is up to date. Nothing to do.\n", plan.Dest)
+		os.Exit(exitNoChanges)
+	}
+
+	printPlan(os.Stdout, plan)
+	fmt.Println()
+
+	if err := applyChanges(summary, plan.Dest); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	os.Exit(exitChanged)
+}
+
 func isValidRepo(repo string) bool {
 	parts := strings.Split(repo, "/")
+	if len(parts) == 3 && isHostname(parts[0]) {
+		parts = parts[1:]
+	}
 	return len(parts) == 2 && parts[0] != "" && parts[1] != ""
-}
\ No newline at end of file
+}
+
+// Splits HOST/owner/repo into its host and owner/repo. Repos without a
+// host live on --hostname.
+func splitRepo(repo string) (host, path string) {
+	parts := strings.SplitN(repo, "/", 3)
+	if len(parts) == 3 && isHostname(parts[0]) {
+		return strings.ToLower(parts[0]), parts[1] + "/" + parts[2]
+	}
+
+	host = strings.ToLower(hostname)
+	if host == "" {
+		host = "github.com"
+	}
+	return host, repo
+}
+
+// Owner names can't contain dots or colons, so a first segment with
+// one is a host like ghe.example.com or localhost:8080
+func isHostname(segment string) bool {
+	return strings.ContainsAny(segment, ".:")
+}
+
+// Defaults --hostname to GH_HOST like the GitHub CLI does
+func defaultHostname() string {
+	if host := os.Getenv("GH_HOST"); host != "" {
+		return host
+	}
+	return "github.com"
+}
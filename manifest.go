@@ -0,0 +1,616 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Manifest is a declarative label set stored in a local file
+type Manifest struct {
+	Labels  []Label
+	Renames map[string]string // Old destination name → new label name
+	Merges  map[string]string // Old destination name → label its issues move to
+
+	// Patterns of destination labels gabel must never change or delete
+	Protected []string
+
+	// mode: additive, so destination labels are never deleted
+	Additive bool
+
+	// With several sources, the source of each label by lowercase name,
+	// and the labels they disagree on
+	Origins   map[string]string
+	Conflicts []SourceConflict
+}
+
+// The contents of a manifest file, before validation
+type manifestFile struct {
+	Entries   []manifestEntry
+	Renames   []manifestMapping
+	Merges    []manifestMapping
+	Protected []string
+	Mode      string
+	ModeLine  int
+}
+
+// A label read from a manifest, with the line it starts on (0 if unknown)
+type manifestEntry struct {
+	Label Label
+	Line  int
+}
+
+// An old name → label name pair from a renames or merges section
+type manifestMapping struct {
+	From string
+	To   string
+	Line int
+}
+
+var tomlLabelsTable = regexp.MustCompile(`^\s*\[\[\s*"?labels"?\s*\]\]`)
+
+// Reports whether a source argument refers to a local manifest file
+func isManifestPath(source string) bool {
+	switch strings.ToLower(filepath.Ext(source)) {
+	case ".yaml", ".yml", ".json", ".toml", ".csv", ".md", ".markdown":
+		return true
+	}
+	return false
+}
+
+// Loads labels from one or more comma-separated sources, each a local
+// manifest or a remote repository, highest priority first
+func loadSource(arg string) (Manifest, error) {
+	sources := splitSources(arg)
+	if len(sources) == 1 {
+		return loadOneSource(sources[0])
+	}
+
+	manifests := make([]Manifest, len(sources))
+	for i, source := range sources {
+		manifest, err := loadOneSource(source)
+		if err != nil {
+			return Manifest{}, fmt.Errorf("%s: %w", source, err)
+		}
+		manifests[i] = manifest
+	}
+	return mergeSources(sources, manifests), nil
+}
+
+// Loads labels from a local manifest or a remote repository
+func loadOneSource(source string) (Manifest, error) {
+	if !isManifestPath(source) {
+		labels, err := FetchLabels(source)
+		return Manifest{Labels: labels}, err
+	}
+
+	return loadManifest(source)
+}
+
+// Reads and validates a YAML, JSON, TOML, CSV or Markdown manifest
+func loadManifest(path string) (Manifest, error) {
+	LogDebug("Loading manifest %s", path)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("failed to read manifest: %v", err)
+	}
+
+	var file manifestFile
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		file, err = parseJSONManifest(data)
+	case ".toml":
+		file, err = parseTOMLManifest(data)
+	case ".csv":
+		file.Entries, err = parseCSVManifest(data)
+	case ".md", ".markdown":
+		file.Entries, err = parseMarkdownManifest(data)
+	default:
+		file, err = parseYAMLManifest(data)
+	}
+	if err != nil {
+		return Manifest{}, fmt.Errorf("failed to parse manifest %s: %v", path, err)
+	}
+
+	if err := validateManifest(path, file); err != nil {
+		return Manifest{}, err
+	}
+
+	manifest := Manifest{
+		Labels:    []Label{},
+		Renames:   mappingsMap(file.Renames),
+		Merges:    mappingsMap(file.Merges),
+		Protected: file.Protected,
+		Additive:  file.Mode == modeAdditive,
+	}
+	for _, entry := range file.Entries {
+		manifest.Labels = append(manifest.Labels, entry.Label)
+	}
+
+	LogDebug("Loaded %d labels from %s", len(manifest.Labels), path)
+	return manifest, nil
+}
+
+func mappingsMap(mappings []manifestMapping) map[string]string {
+	if len(mappings) == 0 {
+		return nil
+	}
+	m := make(map[string]string)
+	for _, mapping := range mappings {
+		m[mapping.From] = mapping.To
+	}
+	return m
+}
+
+// Validates every entry, rename and merge and reports all problems with their line numbers
+func validateManifest(path string, file manifestFile) error {
+	var problems []string
+	seen := make(map[string]string)
+
+	for i, entry := range file.Entries {
+		where := fmt.Sprintf("%s: label %d", path, i+1)
+		if entry.Line > 0 {
+			where = fmt.Sprintf("%s:%d", path, entry.Line)
+		}
+
+		if err := validateLabel(entry.Label); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", where, err))
+			continue
+		}
+
+		key := strings.ToLower(entry.Label.Name)
+		if first, exists := seen[key]; exists {
+			problems = append(problems, fmt.Sprintf("%s: duplicate label %q (first defined at %s)", where, entry.Label.Name, first))
+			continue
+		}
+		seen[key] = where
+	}
+
+	// Renames and merges must turn an old name into one of the manifest's labels
+	claimed := make(map[string]string)
+	problems = append(problems, validateMappings(path, "rename", "renamed", file.Renames, seen, claimed)...)
+	problems = append(problems, validateMappings(path, "merge", "merged", file.Merges, seen, claimed)...)
+
+	// Protected patterns are globs, so only empty ones are invalid
+	for i, pattern := range file.Protected {
+		if strings.TrimSpace(pattern) == "" {
+			problems = append(problems, fmt.Sprintf("%s: protected pattern %d is empty", path, i+1))
+		}
+	}
+
+	if file.Mode != "" && file.Mode != modeAdditive {
+		where := path
+		if file.ModeLine > 0 {
+			where = fmt.Sprintf("%s:%d", path, file.ModeLine)
+		}
+		problems = append(problems, fmt.Sprintf("%s: unknown mode %q (the only mode is %q)", where, file.Mode, modeAdditive))
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid manifest:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// Checks the renames or merges of a manifest. claimed records which
+// section each old name was used in, so a name can't be used twice.
+func validateMappings(path, kind, past string, mappings []manifestMapping, labels map[string]string, claimed map[string]string) []string {
+	var problems []string
+	for i, mapping := range mappings {
+		where := fmt.Sprintf("%s: %s %d", path, kind, i+1)
+		if mapping.Line > 0 {
+			where = fmt.Sprintf("%s:%d", path, mapping.Line)
+		}
+
+		from := strings.ToLower(mapping.From)
+		switch {
+		case mapping.From == "" || mapping.To == "":
+			problems = append(problems, fmt.Sprintf("%s: %s needs an old and a new name", where, kind))
+		case strings.EqualFold(mapping.From, mapping.To):
+			problems = append(problems, fmt.Sprintf("%s: %q is %s into itself", where, mapping.From, past))
+		case labels[from] != "":
+			problems = append(problems, fmt.Sprintf("%s: cannot %s %q, it is still a label in the manifest", where, kind, mapping.From))
+		case labels[strings.ToLower(mapping.To)] == "":
+			problems = append(problems, fmt.Sprintf("%s: %s target %q is not a label in the manifest", where, kind, mapping.To))
+		case claimed[from] == kind:
+			problems = append(problems, fmt.Sprintf("%s: %q is %s more than once", where, mapping.From, past))
+		case claimed[from] != "":
+			problems = append(problems, fmt.Sprintf("%s: %q is both renamed and merged", where, mapping.From))
+		}
+		if claimed[from] == "" {
+			claimed[from] = kind
+		}
+	}
+	return problems
+}
+
+// Parses a YAML manifest: either a list of labels or a mapping with
+// labels, renames, merges, protected and mode keys
+func parseYAMLManifest(data []byte) (manifestFile, error) {
+	var file manifestFile
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return file, err
+	}
+	if len(doc.Content) == 0 {
+		return file, nil
+	}
+
+	root := doc.Content[0]
+	switch root.Kind {
+	case yaml.SequenceNode:
+		entries, err := parseYAMLLabels(root)
+		file.Entries = entries
+		return file, err
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(root.Content); i += 2 {
+			key, value := root.Content[i], root.Content[i+1]
+			var err error
+			switch key.Value {
+			case "labels":
+				file.Entries, err = parseYAMLLabels(value)
+			case "renames":
+				file.Renames, err = parseYAMLMappings(key.Value, value)
+			case "merges":
+				file.Merges, err = parseYAMLMappings(key.Value, value)
+			case "protected":
+				file.Protected, err = parseYAMLStrings(key.Value, value)
+			case "mode":
+				if value.Kind != yaml.ScalarNode {
+					err = fmt.Errorf("line %d: mode must be a string", value.Line)
+				}
+				file.Mode, file.ModeLine = value.Value, value.Line
+			default:
+				err = fmt.Errorf("line %d: unknown section %q", key.Line, key.Value)
+			}
+			if err != nil {
+				return file, err
+			}
+		}
+		return file, nil
+	}
+
+	return file, fmt.Errorf("line %d: expected a list of labels", root.Line)
+}
+
+// Parses a YAML mapping of old names to label names
+func parseYAMLMappings(section string, node *yaml.Node) ([]manifestMapping, error) {
+	if node.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("line %d: %s must map old names to label names", node.Line, section)
+	}
+
+	var mappings []manifestMapping
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		from, to := node.Content[i], node.Content[i+1]
+		if to.Kind != yaml.ScalarNode {
+			return nil, fmt.Errorf("line %d: label name for %s must be a string", to.Line, from.Value)
+		}
+		mappings = append(mappings, manifestMapping{From: from.Value, To: to.Value, Line: from.Line})
+	}
+	return mappings, nil
+}
+
+// Parses a YAML list of strings, such as label patterns
+func parseYAMLStrings(section string, node *yaml.Node) ([]string, error) {
+	if node.Kind != yaml.SequenceNode {
+		return nil, fmt.Errorf("line %d: %s must be a list", node.Line, section)
+	}
+
+	values := []string{}
+	for _, item := range node.Content {
+		if item.Kind != yaml.ScalarNode {
+			return nil, fmt.Errorf("line %d: each entry in %s must be a string", item.Line, section)
+		}
+		values = append(values, item.Value)
+	}
+	return values, nil
+}
+
+// Parses a YAML sequence of label mappings. Values are read as raw
+// scalars so colors like 000000 are not turned into numbers. Other
+// fields, such as the id and url in GitHub API output, are ignored.
+func parseYAMLLabels(node *yaml.Node) ([]manifestEntry, error) {
+	if node.Kind != yaml.SequenceNode {
+		return nil, fmt.Errorf("line %d: labels must be a list", node.Line)
+	}
+
+	entries := []manifestEntry{}
+	for _, item := range node.Content {
+		if item.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("line %d: each label must be a mapping with name, color and description", item.Line)
+		}
+
+		entry := manifestEntry{Line: item.Line}
+		for i := 0; i+1 < len(item.Content); i += 2 {
+			key, value := item.Content[i], item.Content[i+1]
+			if value.Kind != yaml.ScalarNode {
+				return nil, fmt.Errorf("line %d: %s must be a string", value.Line, key.Value)
+			}
+
+			switch key.Value {
+			case "name":
+				entry.Label.Name = value.Value
+			case "color":
+				entry.Label.Color = value.Value
+			case "description":
+				entry.Label.Description = value.Value
+			}
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// Parses a JSON manifest: either an array of labels or an object with
+// labels, renames, merges, protected and mode keys
+func parseJSONManifest(data []byte) (manifestFile, error) {
+	var file manifestFile
+	dec := json.NewDecoder(bytes.NewReader(data))
+
+	tok, err := dec.Token()
+	if err != nil {
+		return file, err
+	}
+
+	if tok == json.Delim('[') {
+		file.Entries, err = parseJSONLabels(dec, data)
+		return file, err
+	}
+	if tok != json.Delim('{') {
+		return file, fmt.Errorf("line 1: expected a list of labels")
+	}
+
+	for dec.More() {
+		keyTok, err := dec.Token()
+		if err != nil {
+			return file, err
+		}
+		key, _ := keyTok.(string)
+
+		switch key {
+		case "labels":
+			if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+				return file, fmt.Errorf("line %d: labels must be a list", lineAt(data, dec.InputOffset()))
+			}
+			file.Entries, err = parseJSONLabels(dec, data)
+		case "renames":
+			file.Renames, err = parseJSONMappings(key, dec, data)
+		case "merges":
+			file.Merges, err = parseJSONMappings(key, dec, data)
+		case "protected":
+			line := lineAt(data, dec.InputOffset())
+			if decodeErr := dec.Decode(&file.Protected); decodeErr != nil {
+				err = fmt.Errorf("line %d: protected must be a list of strings", line)
+			}
+		case "mode":
+			file.ModeLine = lineAt(data, dec.InputOffset())
+			if decodeErr := dec.Decode(&file.Mode); decodeErr != nil {
+				err = fmt.Errorf("line %d: mode must be a string", file.ModeLine)
+			}
+		default:
+			err = fmt.Errorf("line %d: unknown section %q", lineAt(data, dec.InputOffset()), key)
+		}
+		if err != nil {
+			return file, err
+		}
+	}
+
+	return file, nil
+}
+
+// Decodes a JSON object mapping old names to label names
+func parseJSONMappings(section string, dec *json.Decoder, data []byte) ([]manifestMapping, error) {
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, fmt.Errorf("line %d: %s must map old names to label names", lineAt(data, dec.InputOffset()), section)
+	}
+
+	mappings := []manifestMapping{}
+	for dec.More() {
+		line := lineAt(data, dec.InputOffset())
+		keyTok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		from, _ := keyTok.(string)
+
+		var to string
+		if err := dec.Decode(&to); err != nil {
+			return nil, fmt.Errorf("line %d: label name for %s must be a string", line, from)
+		}
+		mappings = append(mappings, manifestMapping{From: from, To: to, Line: line})
+	}
+
+	// Closing brace
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	return mappings, nil
+}
+
+// Decodes the elements of a JSON array of labels, after its opening bracket
+func parseJSONLabels(dec *json.Decoder, data []byte) ([]manifestEntry, error) {
+	entries := []manifestEntry{}
+	for dec.More() {
+		line := lineAt(data, dec.InputOffset())
+
+		var label Label
+		if err := dec.Decode(&label); err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		entries = append(entries, manifestEntry{Label: label, Line: line})
+	}
+
+	// Closing bracket
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// Returns the line of the first non-space character at or after offset
+func lineAt(data []byte, offset int64) int {
+	i := int(offset)
+	for i < len(data) && strings.ContainsRune(" \t\r\n,:", rune(data[i])) {
+		i++
+	}
+	return bytes.Count(data[:i], []byte("\n")) + 1
+}
+
+// Parses a TOML manifest made of [[labels]] tables, optional [renames]
+// and [merges] tables, and optional protected and mode keys
+func parseTOMLManifest(data []byte) (manifestFile, error) {
+	var file manifestFile
+	var doc struct {
+		Labels    []Label           `toml:"labels"`
+		Renames   map[string]string `toml:"renames"`
+		Merges    map[string]string `toml:"merges"`
+		Protected []string          `toml:"protected"`
+		Mode      string            `toml:"mode"`
+	}
+
+	meta, err := toml.Decode(string(data), &doc)
+	if err != nil {
+		return file, err
+	}
+
+	// The TOML decoder doesn't report positions, so find each [[labels]] header
+	var lines []int
+	for i, line := range strings.Split(string(data), "\n") {
+		if tomlLabelsTable.MatchString(line) {
+			lines = append(lines, i+1)
+		}
+	}
+
+	file.Protected, file.Mode = doc.Protected, doc.Mode
+	file.Entries = []manifestEntry{}
+	for i, label := range doc.Labels {
+		entry := manifestEntry{Label: label}
+		if len(lines) == len(doc.Labels) {
+			entry.Line = lines[i]
+		}
+		file.Entries = append(file.Entries, entry)
+	}
+
+	// Keep renames and merges in file order
+	for _, key := range meta.Keys() {
+		if len(key) != 2 {
+			continue
+		}
+		switch key[0] {
+		case "renames":
+			file.Renames = append(file.Renames, manifestMapping{From: key[1], To: doc.Renames[key[1]]})
+		case "merges":
+			file.Merges = append(file.Merges, manifestMapping{From: key[1], To: doc.Merges[key[1]]})
+		}
+	}
+	return file, nil
+}
+
+// Parses a CSV manifest with name, color and description columns.
+// A header row naming the columns is optional.
+func parseCSVManifest(data []byte) ([]manifestEntry, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+
+	entries := []manifestEntry{}
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		line, _ := r.FieldPos(0)
+		if len(record) < 2 || len(record) > 3 {
+			return nil, fmt.Errorf("line %d: expected name,color,description", line)
+		}
+		if len(entries) == 0 && strings.EqualFold(record[0], "name") && strings.EqualFold(record[1], "color") {
+			continue
+		}
+
+		entry := manifestEntry{Label: Label{Name: record[0], Color: record[1]}, Line: line}
+		if len(record) == 3 {
+			entry.Label.Description = record[2]
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// Parses a Markdown table with Name, Color and Description columns, as
+// written by gabel export. Anything outside the table is ignored.
+func parseMarkdownManifest(data []byte) ([]manifestEntry, error) {
+	entries := []manifestEntry{}
+	inTable := false
+
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "|") {
+			if inTable {
+				break
+			}
+			continue
+		}
+
+		cells := splitMarkdownRow(line)
+		if !inTable {
+			// Header row
+			inTable = true
+			continue
+		}
+		if strings.Trim(strings.Join(cells, ""), "-: ") == "" {
+			// Separator row
+			continue
+		}
+
+		if len(cells) < 2 || len(cells) > 3 {
+			return nil, fmt.Errorf("line %d: expected | name | color | description |", i+1)
+		}
+
+		entry := manifestEntry{
+			Label: Label{Name: cells[0], Color: strings.Trim(cells[1], "`")},
+			Line:  i + 1,
+		}
+		if len(cells) == 3 {
+			entry.Label.Description = cells[2]
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// Splits a Markdown table row into trimmed cells, honoring \| escapes
+func splitMarkdownRow(line string) []string {
+	line = strings.TrimSuffix(strings.TrimPrefix(line, "|"), "|")
+
+	var cells []string
+	var cell strings.Builder
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '\\' && i+1 < len(line):
+			i++
+			cell.WriteByte(line[i])
+		case line[i] == '|':
+			cells = append(cells, strings.TrimSpace(cell.String()))
+			cell.Reset()
+		default:
+			cell.WriteByte(line[i])
+		}
+	}
+	return append(cells, strings.TrimSpace(cell.String()))
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Writes a manifest to a temp dir and returns its path
+func writeManifest(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadManifestFormats(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"labels.yaml", `labels:
+  - name: bug
+    color: d73a4a
+    description: Something isn't working
+  - name: black
+    color: 000000
+`},
+		{"labels.yml", `- name: bug
+  color: "#d73a4a"
+  description: Something isn't working
+- name: black
+  color: "000000"
+`},
+		{"labels.json", `{
+  "labels": [
+    {"name": "bug", "color": "d73a4a", "description": "Something isn't working"},
+    {"name": "black", "color": "000000", "id": 42}
+  ]
+}`},
+		{"labels.toml", `[[labels]]
+name = "bug"
+color = "d73a4a"
+description = "Something isn't working"
+
+[[labels]]
+name = "black"
+color = "000000"
+`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manifest, err := loadManifest(writeManifest(t, tt.name, tt.content))
+			if err != nil {
+				t.Fatalf("loadManifest() error = %v", err)
+			}
+
+			if len(manifest.Labels) != 2 {
+				t.Fatalf("Expected 2 labels, got %d", len(manifest.Labels))
+			}
+			if manifest.Labels[0].Name != "bug" || manifest.Labels[0].Description != "Something isn't working" {
+				t.Errorf("Unexpected first label %+v", manifest.Labels[0])
+			}
+			if normalizeColor(manifest.Labels[1].Color) != "000000" {
+				t.Errorf("Color should keep leading zeros, got %q", manifest.Labels[1].Color)
+			}
+		})
+	}
+}
+
+func TestLoadManifestLineNumbers(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{"labels.yaml", `labels:
+  - name: bug
+    color: d73a4a
+  - name: ""
+    color: d73a4a
+  - name: feature
+    color: blue
+  - name: BUG
+    color: ff0000
+`, []string{":4: label name cannot be empty", ":6: invalid color", `:8: duplicate label "BUG"`}},
+		{"labels.json", `[
+  {"name": "bug", "color": "d73a4a"},
+  {"name": "feature", "color": "blue"}
+]`, []string{":3: invalid color"}},
+		{"labels.toml", `[[labels]]
+name = "bug"
+color = "d73a4a"
+
+[[labels]]
+name = "feature"
+color = "blue"
+`, []string{":5: invalid color"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadManifest(writeManifest(t, tt.name, tt.content))
+			if err == nil {
+				t.Fatal("Expected validation error")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), tt.name+want) {
+					t.Errorf("Error should contain %q, got:\n%v", tt.name+want, err)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadManifestRenames(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"labels.yaml", `labels:
+  - name: "type: bug"
+    color: d73a4a
+renames:
+  bug: "type: bug"
+`},
+		{"labels.json", `{
+  "labels": [{"name": "type: bug", "color": "d73a4a"}],
+  "renames": {"bug": "type: bug"}
+}`},
+		{"labels.toml", `[[labels]]
+name = "type: bug"
+color = "d73a4a"
+
+[renames]
+bug = "type: bug"
+`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manifest, err := loadManifest(writeManifest(t, tt.name, tt.content))
+			if err != nil {
+				t.Fatalf("loadManifest() error = %v", err)
+			}
+			if len(manifest.Renames) != 1 || manifest.Renames["bug"] != "type: bug" {
+				t.Errorf("Renames = %v, want map[bug:type: bug]", manifest.Renames)
+			}
+		})
+	}
+}
+
+func TestLoadManifestMerges(t *testing.T) {
+	path := writeManifest(t, "labels.yaml", `labels:
+  - name: bug
+    color: d73a4a
+merges:
+  defect: bug
+  broken: bug
+`)
+
+	manifest, err := loadManifest(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(manifest.Merges) != 2 || manifest.Merges["defect"] != "bug" || manifest.Merges["broken"] != "bug" {
+		t.Errorf("Merges = %v", manifest.Merges)
+	}
+
+	path = writeManifest(t, "labels.json", `{
+  "labels": [{"name": "bug", "color": "d73a4a"}],
+  "renames": {"defect": "bug"},
+  "merges": {"defect": "bug", "feature": "enhancement"}
+}`)
+	_, err = loadManifest(path)
+	if err == nil {
+		t.Fatal("Expected validation error")
+	}
+	for _, want := range []string{`labels.json:4: "defect" is both renamed and merged`, `labels.json:4: merge target "enhancement" is not a label`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Error should contain %q, got:\n%v", want, err)
+		}
+	}
+}
+
+func TestLoadManifestProtected(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"labels.yaml", "labels:\n  - name: bug\n    color: d73a4a\nprotected:\n  - release/*\n  - security\n"},
+		{"labels.json", `{"labels": [{"name": "bug", "color": "d73a4a"}], "protected": ["release/*", "security"]}`},
+		{"labels.toml", "protected = [\"release/*\", \"security\"]\n\n[[labels]]\nname = \"bug\"\ncolor = \"d73a4a\"\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manifest, err := loadManifest(writeManifest(t, tt.name, tt.content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(manifest.Protected, ",") != "release/*,security" {
+				t.Errorf("Protected = %v, want [release/* security]", manifest.Protected)
+			}
+		})
+	}
+
+	_, err := loadManifest(writeManifest(t, "labels.yaml", "labels:\n  - name: bug\n    color: d73a4a\nprotected: security\n"))
+	if err == nil || !strings.Contains(err.Error(), "protected must be a list") {
+		t.Errorf("Expected a list error, got %v", err)
+	}
+}
+
+func TestLoadManifestMode(t *testing.T) {
+	for name, content := range map[string]string{
+		"labels.yaml": "mode: additive\nlabels:\n  - name: bug\n    color: d73a4a\n",
+		"labels.json": `{"mode": "additive", "labels": [{"name": "bug", "color": "d73a4a"}]}`,
+		"labels.toml": "mode = \"additive\"\n\n[[labels]]\nname = \"bug\"\ncolor = \"d73a4a\"\n",
+	} {
+		manifest, err := loadManifest(writeManifest(t, name, content))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !manifest.Additive {
+			t.Errorf("%s: expected an additive manifest", name)
+		}
+	}
+
+	_, err := loadManifest(writeManifest(t, "labels.yaml", "labels:\n  - name: bug\n    color: d73a4a\nmode: addtive\n"))
+	if err == nil || !strings.Contains(err.Error(), `labels.yaml:4: unknown mode "addtive"`) {
+		t.Errorf("Expected an unknown mode error, got %v", err)
+	}
+}
+
+func TestLoadManifestInvalidRenames(t *testing.T) {
+	path := writeManifest(t, "labels.yaml", `labels:
+  - name: "type: bug"
+    color: d73a4a
+  - name: docs
+    color: 0075ca
+renames:
+  bug: "type: bug"
+  docs: documentation
+  feature: enhancement
+  Bug: "type: bug"
+`)
+
+	_, err := loadManifest(path)
+	if err == nil {
+		t.Fatal("Expected validation error")
+	}
+	for _, want := range []string{
+		`labels.yaml:8: cannot rename "docs"`,
+		`labels.yaml:9: rename target "enhancement" is not a label`,
+		`labels.yaml:10: "Bug" is renamed more than once`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Error should contain %q, got:\n%v", want, err)
+		}
+	}
+}
+
+func TestLoadManifestUnknownSection(t *testing.T) {
+	path := writeManifest(t, "labels.yaml", "lables:\n  - name: bug\n")
+
+	_, err := loadManifest(path)
+	if err == nil || !strings.Contains(err.Error(), `unknown section "lables"`) {
+		t.Errorf("Expected unknown section error, got %v", err)
+	}
+}
+
+func TestIsManifestPath(t *testing.T) {
+	tests := []struct {
+		source string
+		want   bool
+	}{
+		{"labels.yaml", true},
+		{"config/labels.YML", true},
+		{"./labels.json", true},
+		{"labels.toml", true},
+		{"owner/repo", false},
+		{"owner/repo.js", false},
+	}
+
+	for _, tt := range tests {
+		if got := isManifestPath(tt.source); got != tt.want {
+			t.Errorf("isManifestPath(%q) = %v, want %v", tt.source, got, tt.want)
+		}
+	}
+}
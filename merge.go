@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Marks destination-only labels that the manifest merges into another label
+func markMerges(items []PickerItem, sourceLabels []Label, merges map[string]string) {
+	if len(merges) == 0 {
+		return
+	}
+
+	sourceMap := make(map[string]bool)
+	for _, label := range sourceLabels {
+		sourceMap[strings.ToLower(label.Name)] = true
+	}
+
+	for i := range items {
+		item := &items[i]
+		if !item.IsDestOnly || sourceMap[strings.ToLower(item.Label.Name)] {
+			continue
+		}
+		for from, into := range merges {
+			if strings.EqualFold(from, item.Label.Name) {
+				item.MergeInto = into
+			}
+		}
+	}
+}
+
+// Finds the label an item can be merged into, matching the name
+// case-insensitively against every other label in the picker
+func findMergeTarget(items []PickerItem, index int, name string) (string, bool) {
+	for i, item := range items {
+		if i != index && item.MergeInto == "" && strings.EqualFold(item.Label.Name, strings.TrimSpace(name)) {
+			return item.Label.Name, true
+		}
+	}
+	return "", false
+}
+
+// Returns the chosen merges, old name → target name
+func itemMerges(items []PickerItem) map[string]string {
+	merges := make(map[string]string)
+	for _, item := range items {
+		if item.MergeInto != "" && !item.Selected {
+			merges[strings.ToLower(item.Label.Name)] = item.MergeInto
+		}
+	}
+	return merges
+}
+
+// Turns the deletes of merged labels into merges. A merge only happens
+// if its target is part of the destination's final state.
+func applyMerges(summary ActionSummary, merges map[string]string) ActionSummary {
+	if len(merges) == 0 {
+		return summary
+	}
+
+	final := append(append(append([]Label{}, summary.ToCreate...), summary.ToUpdate...), summary.ToKeep...)
+	for _, rename := range summary.ToRename {
+		final = append(final, rename.To)
+	}
+
+	summary.ToMerge = []LabelMerge{}
+	var deletes []Label
+	for _, label := range summary.ToDelete {
+		into, merged := merges[strings.ToLower(label.Name)]
+		target := indexOfLabel(final, into)
+		if !merged || target < 0 {
+			deletes = append(deletes, label)
+			continue
+		}
+		summary.ToMerge = append(summary.ToMerge, LabelMerge{From: label.Name, Into: final[target]})
+	}
+	summary.ToDelete = deletes
+	if summary.ToDelete == nil {
+		summary.ToDelete = []Label{}
+	}
+
+	sort.Slice(summary.ToMerge, func(i, j int) bool {
+		return strings.ToLower(summary.ToMerge[i].From) < strings.ToLower(summary.ToMerge[j].From)
+	})
+	return summary
+}
+
+// Moves every issue and PR from one label to another, then deletes the
+// old label. Each issue gets the new label before losing the old one, so
+// an interrupted merge picks up where it stopped when run again.
+func mergeLabel(out io.Writer, repo, from string, into Label) error {
+	issues, err := ListLabeledIssues(repo, from)
+	if err != nil {
+		return err
+	}
+
+	for i, issue := range issues {
+		fmt.Fprintf(out, "      [%d/%d] #%d %s\n", i+1, len(issues), issue.Number, issue.Title)
+		if err := AddIssueLabel(repo, issue.Number, into.Name); err != nil {
+			return fmt.Errorf("failed to add %s to #%d: %v", into.Name, issue.Number, err)
+		}
+		if err := RemoveIssueLabel(repo, issue.Number, from); err != nil && !errors.Is(err, ErrNotFound) {
+			return fmt.Errorf("failed to remove %s from #%d: %v", from, issue.Number, err)
+		}
+	}
+
+	return DeleteLabel(repo, from)
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSummarizeMerges(t *testing.T) {
+	source := Manifest{
+		Labels: []Label{{Name: "bug", Color: "d73a4a"}},
+		Merges: map[string]string{"defect": "bug"},
+	}
+	destLabels := []Label{
+		{Name: "Defect", Color: "ee0701"},
+		{Name: "wontfix", Color: "ffffff"},
+	}
+
+	// Merges apply without --prune, and other dest labels are kept
+	items := prepareItems(source, destLabels)
+	summary := summarize(items, destLabels)
+
+	if len(summary.ToMerge) != 1 || summary.ToMerge[0].From != "Defect" || summary.ToMerge[0].Into.Name != "bug" {
+		t.Fatalf("ToMerge = %+v, want Defect → bug", summary.ToMerge)
+	}
+	if len(summary.ToDelete) != 0 || len(summary.ToCreate) != 1 {
+		t.Errorf("Unexpected summary %+v", summary)
+	}
+
+	ops := planOperations(summary)
+	if last := ops[len(ops)-1]; last.Action != "merge" {
+		t.Errorf("Merges should run after creates, got %+v", ops)
+	}
+}
+
+func TestApplyMergesNeedsTarget(t *testing.T) {
+	summary := ActionSummary{
+		ToDelete: []Label{{Name: "defect"}},
+		ToKeep:   []Label{{Name: "wontfix"}},
+	}
+
+	// bug isn't part of the final state, so defect is plainly deleted
+	summary = applyMerges(summary, map[string]string{"defect": "bug"})
+	if len(summary.ToMerge) != 0 || len(summary.ToDelete) != 1 {
+		t.Errorf("Merge without a target should stay a delete, got %+v", summary)
+	}
+}
+
+func TestFindMergeTarget(t *testing.T) {
+	items := []PickerItem{
+		{Label: Label{Name: "defect"}, IsDestOnly: true},
+		{Label: Label{Name: "Bug"}},
+		{Label: Label{Name: "old"}, IsDestOnly: true, MergeInto: "Bug"},
+	}
+
+	if target, found := findMergeTarget(items, 0, " bug "); !found || target != "Bug" {
+		t.Errorf("findMergeTarget(bug) = %q, %v", target, found)
+	}
+	if _, found := findMergeTarget(items, 0, "defect"); found {
+		t.Error("A label cannot be merged into itself")
+	}
+	if _, found := findMergeTarget(items, 0, "old"); found {
+		t.Error("A label that is merged away cannot be a target")
+	}
+}
+
+func TestMergeLabel(t *testing.T) {
+	ms := useMemStore(t)
+	ms.labels["owner/repo"] = []Label{{Name: "defect"}, {Name: "bug"}}
+	ms.issues["owner/repo"] = []Issue{
+		{Number: 1, Title: "Crash", Labels: []Label{{Name: "defect"}}},
+		{Number: 2, Title: "Typo", Labels: []Label{{Name: "defect"}, {Name: "bug"}}},
+		{Number: 3, Title: "Feature", Labels: []Label{{Name: "enhancement"}}},
+	}
+
+	var out strings.Builder
+	op := Operation{Action: "merge", From: "defect", Label: Label{Name: "bug", Color: "d73a4a"}}
+	if err := applyOperation(&out, "owner/repo", op, 1, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, issue := range ms.issues["owner/repo"][:2] {
+		if indexOfLabel(issue.Labels, "bug") < 0 || indexOfLabel(issue.Labels, "defect") >= 0 {
+			t.Errorf("#%d labels = %v, want bug without defect", issue.Number, issue.Labels)
+		}
+	}
+	if indexOfLabel(ms.labels["owner/repo"], "defect") >= 0 {
+		t.Error("defect should be deleted after the merge")
+	}
+
+	for _, want := range []string{"[1/1] Merging defect into bug...", "[1/2] #1 Crash", "[2/2] #2 Typo"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Output should contain %q, got:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestMergePlanRoundTrip(t *testing.T) {
+	plan := Plan{Dest: "owner/repo", Operations: []Operation{{Action: "merge", From: "defect", Label: Label{Name: "bug"}}}}
+
+	summary, err := planSummary(plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(summary.ToMerge) != 1 || summary.ToMerge[0].From != "defect" {
+		t.Errorf("Unexpected summary %+v", summary)
+	}
+
+	got := describeOperation("owner/repo", plan.Operations[0])
+	if !strings.HasPrefix(got, `> MERGE  repos/owner/repo/labels/defect  into="bug"`) {
+		t.Errorf("describeOperation() = %q", got)
+	}
+}
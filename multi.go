@@ -0,0 +1,277 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/manifoldco/promptui"
+)
+
+// The outcome of syncing one destination in a multi-repo run
+type repoResult struct {
+	Repo       string
+	DestLabels []Label
+	Summary    ActionSummary
+	Err        error
+	Applied    bool
+	InUse      []inUseLabel // Deletes kept because the labels are in use
+	UsageErr   error
+}
+
+// Collects destination repos from arguments and --dest-file
+func destinationArgs(args []string) ([]string, error) {
+	dests := append([]string{}, args...)
+
+	if destFile != "" {
+		f, err := os.Open(destFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read destination list: %v", err)
+		}
+		defer func() { _ = f.Close() }()
+
+		// One repo per line; blank lines and # comments are ignored
+		scanner := bufio.NewScanner(f)
+		for lineNum := 1; scanner.Scan(); lineNum++ {
+			line := strings.TrimSpace(scanner.Text())
+			if i := strings.Index(line, "#"); i >= 0 {
+				line = strings.TrimSpace(line[:i])
+			}
+			if line == "" {
+				continue
+			}
+			if !isValidRepo(line) {
+				return nil, fmt.Errorf("%s:%d: invalid repo %q", destFile, lineNum, line)
+			}
+			dests = append(dests, line)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read destination list: %v", err)
+		}
+	}
+
+	return dests, nil
+}
+
+// Lists the repos of an organization that match --topic and --match.
+// Archived repos are read-only, so they are always skipped.
+func orgDestinations(org, topic string, match *regexp.Regexp) ([]string, error) {
+	repos, err := store.ListOrgRepos(org)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list repos in %s: %w", org, err)
+	}
+
+	var dests []string
+	for _, repo := range repos {
+		if repo.Archived {
+			LogDebug("Skipping archived repo %s", repo.FullName)
+			continue
+		}
+		if topic != "" && !containsFold(repo.Topics, topic) {
+			continue
+		}
+		if match != nil && !match.MatchString(repo.Name) {
+			continue
+		}
+		dests = append(dests, repo.FullName)
+	}
+
+	LogDebug("Found %d matching repos out of %d in %s", len(dests), len(repos), org)
+	return dests, nil
+}
+
+func containsFold(values []string, want string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, want) {
+			return true
+		}
+	}
+	return false
+}
+
+// Removes duplicate repos, and the sources themselves, keeping the first occurrence
+func uniqueDestinations(dests []string, sourceRepo string) []string {
+	seen := make(map[string]bool)
+	for _, source := range splitSources(sourceRepo) {
+		seen[strings.ToLower(source)] = true
+	}
+	var unique []string
+	for _, dest := range dests {
+		if seen[strings.ToLower(dest)] {
+			continue
+		}
+		seen[strings.ToLower(dest)] = true
+		unique = append(unique, dest)
+	}
+	return unique
+}
+
+// Runs fn for 0..n-1 with at most limit calls in flight
+func forEachParallel(n, limit int, fn func(i int)) {
+	if limit < 1 {
+		limit = 1
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, limit)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			fn(i)
+		}(i)
+	}
+	wg.Wait()
+}
+
+// Syncs one source to several destinations and returns the exit code
+func runMulti(sourceRepo string, source Manifest, dests []string) int {
+	results := make([]repoResult, len(dests))
+
+	fmt.Fprintf(os.Stderr, "Fetching labels from %d repos...\n", len(dests))
+	forEachParallel(len(dests), parallel, func(i int) {
+		result := &results[i]
+		result.Repo = dests[i]
+
+		destLabels, err := FetchLabels(dests[i])
+		if err != nil {
+			result.Err = err
+			return
+		}
+
+		// Suggested renames need the picker, so only explicit ones apply here
+		items := prepareItems(source, destLabels)
+		result.DestLabels = destLabels
+		result.Summary = summarize(items, destLabels)
+		if !deleteInUse {
+			result.Summary, result.InUse, result.UsageErr = keepInUse(dests[i], result.Summary)
+		}
+	})
+
+	if dryRun && outputFormat == "json" {
+		var plans []Plan
+		for _, result := range results {
+			if result.Err != nil {
+				fmt.Fprintf(os.Stderr, "Error fetching labels from %s: %v\n", result.Repo, result.Err)
+				continue
+			}
+			printInUseWarning(os.Stderr, result.Repo, result.InUse, result.UsageErr)
+			plans = append(plans, newPlan(result.Summary, sourceRepo, result.Repo, result.DestLabels))
+		}
+		if err := writePlansJSON(os.Stdout, plans); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return exitError
+		}
+		return multiExitCode(results, false)
+	}
+
+	// Show the plan for every repo
+	changed := 0
+	for _, result := range results {
+		fmt.Println()
+		if result.Err != nil {
+			fmt.Printf("✗ %s: %v\n", result.Repo, result.Err)
+			continue
+		}
+		printPlan(os.Stdout, newPlan(result.Summary, sourceRepo, result.Repo, result.DestLabels))
+		printInUseWarning(os.Stdout, result.Repo, result.InUse, result.UsageErr)
+		if hasChanges(result.Summary) {
+			changed++
+		}
+	}
+
+	fmt.Printf("\n%d of %d repos need changes.\n", changed, len(results))
+	if dryRun || changed == 0 {
+		return multiExitCode(results, false)
+	}
+
+	if !yes {
+		prompt := promptui.Prompt{
+			Label:     fmt.Sprintf("Apply changes to %d repos", changed),
+			IsConfirm: true,
+			Default:   "n",
+		}
+		if _, err := prompt.Run(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: cancelled\n")
+			return exitError
+		}
+
+		// Plans that delete many labels need each repo's name typed
+		if err := confirmMultiDeletes(os.Stdout, results, confirmRepoName); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return exitError
+		}
+	}
+
+	// Apply in parallel, printing each repo's progress once it finishes
+	var mu sync.Mutex
+	forEachParallel(len(results), parallel, func(i int) {
+		result := &results[i]
+		if result.Err != nil || !hasChanges(result.Summary) {
+			return
+		}
+
+		var out bytes.Buffer
+		result.Err = applyChangesTo(&out, result.Summary, result.Repo)
+		result.Applied = result.Err == nil
+
+		mu.Lock()
+		defer mu.Unlock()
+		fmt.Printf("\n── %s ──\n%s", result.Repo, out.String())
+		if result.Err != nil {
+			fmt.Printf("Error: %v\n", result.Err)
+		}
+	})
+
+	fmt.Println()
+	printResultsTable(results)
+	return multiExitCode(results, true)
+}
+
+// Prints a per-repo table of what was done
+func printResultsTable(results []repoResult) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "REPO\tCREATE\tUPDATE\tRENAME\tMERGE\tDELETE\tSTATUS")
+	for _, result := range results {
+		status := "up to date"
+		switch {
+		case result.Err != nil:
+			status = "failed: " + firstLine(result.Err.Error())
+		case result.Applied:
+			status = "ok"
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%d\t%s\n", result.Repo,
+			len(result.Summary.ToCreate), len(result.Summary.ToUpdate), len(result.Summary.ToRename),
+			len(result.Summary.ToMerge), len(result.Summary.ToDelete), status)
+	}
+	_ = w.Flush()
+}
+
+func firstLine(s string) string {
+	if i := strings.Index(s, "\n"); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
+
+// Picks the exit code for a multi-repo run: any failure is an error,
+// otherwise report whether anything changed
+func multiExitCode(results []repoResult, applied bool) int {
+	code := exitNoChanges
+	for _, result := range results {
+		if result.Err != nil {
+			return exitError
+		}
+		if applied && result.Applied {
+			code = exitChanged
+		}
+	}
+	return code
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDestinationArgs(t *testing.T) {
+	oldDestFile := destFile
+	defer func() { destFile = oldDestFile }()
+
+	destFile = filepath.Join(t.TempDir(), "repos.txt")
+	content := "# Backend services\nmyorg/api\n\nmyorg/worker  # queue consumer\nghe.example.com/team/tool\n"
+	if err := os.WriteFile(destFile, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	dests, err := destinationArgs([]string{"myorg/web"})
+	if err != nil {
+		t.Fatalf("destinationArgs() error = %v", err)
+	}
+
+	want := []string{"myorg/web", "myorg/api", "myorg/worker", "ghe.example.com/team/tool"}
+	if !reflect.DeepEqual(dests, want) {
+		t.Errorf("destinationArgs() = %v, want %v", dests, want)
+	}
+
+	if err := os.WriteFile(destFile, []byte("myorg/api\nnot-a-repo\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := destinationArgs(nil); err == nil {
+		t.Error("Expected an error for an invalid repo in the list")
+	}
+}
+
+func TestOrgDestinations(t *testing.T) {
+	m := useMemStore(t)
+	m.repos["myorg"] = []Repo{
+		{FullName: "myorg/api", Name: "api", Topics: []string{"go", "backend"}},
+		{FullName: "myorg/web", Name: "web", Topics: []string{"frontend"}},
+		{FullName: "myorg/api-docs", Name: "api-docs", Topics: []string{"backend"}},
+		{FullName: "myorg/old-api", Name: "old-api", Topics: []string{"backend"}, Archived: true},
+	}
+
+	dests, err := orgDestinations("myorg", "Backend", regexp.MustCompile(`^api`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"myorg/api", "myorg/api-docs"}
+	if !reflect.DeepEqual(dests, want) {
+		t.Errorf("orgDestinations() = %v, want %v", dests, want)
+	}
+}
+
+func TestUniqueDestinations(t *testing.T) {
+	dests := uniqueDestinations([]string{"myorg/a", "myorg/base", "MyOrg/A", "myorg/b"}, "myorg/base")
+
+	want := []string{"myorg/a", "myorg/b"}
+	if !reflect.DeepEqual(dests, want) {
+		t.Errorf("uniqueDestinations() = %v, want %v", dests, want)
+	}
+}
+
+func TestUniqueDestinationsSeveralSources(t *testing.T) {
+	dests := uniqueDestinations([]string{"myorg/a", "myorg/base", "myorg/team"}, "myorg/base,labels.yaml,myorg/team")
+
+	if want := []string{"myorg/a"}; !reflect.DeepEqual(dests, want) {
+		t.Errorf("uniqueDestinations() = %v, want %v", dests, want)
+	}
+}
+
+func TestForEachParallel(t *testing.T) {
+	var running, maxRunning, calls int32
+
+	forEachParallel(20, 3, func(i int) {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			max := atomic.LoadInt32(&maxRunning)
+			if n <= max || atomic.CompareAndSwapInt32(&maxRunning, max, n) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		atomic.AddInt32(&calls, 1)
+	})
+
+	if calls != 20 {
+		t.Errorf("Expected 20 calls, got %d", calls)
+	}
+	if maxRunning > 3 {
+		t.Errorf("Expected at most 3 at once, got %d", maxRunning)
+	}
+}
+
+func TestRunMulti(t *testing.T) {
+	m := useMemStore(t)
+	m.labels["myorg/a"] = []Label{{Name: "bug", Color: "ff0000"}, {Name: "stale", Color: "cccccc"}}
+	m.labels["myorg/b"] = []Label{{Name: "bug", Color: "d73a4a"}, {Name: "feature", Color: "a2eeef"}}
+
+	oldYes, oldPrune := yes, prune
+	defer func() { yes, prune = oldYes, oldPrune }()
+	yes, prune = true, true
+
+	sourceLabels := []Label{{Name: "bug", Color: "d73a4a"}, {Name: "feature", Color: "a2eeef"}}
+
+	code := runMulti("myorg/base", Manifest{Labels: sourceLabels}, []string{"myorg/a", "myorg/b"})
+	if code != exitChanged {
+		t.Errorf("Expected exit code %d, got %d", exitChanged, code)
+	}
+	if !reflect.DeepEqual(m.labels["myorg/a"], sourceLabels) {
+		t.Errorf("myorg/a labels = %v, want %v", m.labels["myorg/a"], sourceLabels)
+	}
+
+	// Nothing left to do, and a missing repo is an error
+	code = runMulti("myorg/base", Manifest{Labels: sourceLabels}, []string{"myorg/a", "myorg/b", "myorg/missing"})
+	if code != exitError {
+		t.Errorf("Expected exit code %d, got %d", exitError, code)
+	}
+}
@@ -2,127 +2,70 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
 	"strings"
 
 	"github.com/manifoldco/promptui"
 	"golang.org/x/term"
 )
 
-// Shows interactive picker and returns selected labels
-func ShowPicker(sourceLabels, destLabels []Label, destRepo string, verbose bool) ([]Label, error) {
+// Shows interactive picker and returns the items with their final selection
+func ShowPicker(items []PickerItem, destRepo string, verbose bool) ([]PickerItem, error) {
 	// Check if we're in an interactive terminal
-	if !term.IsTerminal(int(os.Stdin.Fd())) {
-		return nil, fmt.Errorf("interactive picker requires a terminal")
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return nil, fmt.Errorf("interactive picker requires a terminal (use --yes to apply without prompting)")
 	}
 	
-	items := buildPickerItems(sourceLabels, destLabels)
+	// Raw mode for the whole picker, so keys arrive as they're pressed.
+	// Output then needs \r\n to start each line at the left edge.
+	oldState, err := term.MakeRaw(fd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up the terminal: %v", err)
+	}
+	defer func() { _ = term.Restore(fd, oldState) }()
 	
-	fmt.Printf("\nCurrent state → Desired state for %s:\n\n", destRepo)
+	// Frames don't end in a newline, so end the last one before leaving
+	defer fmt.Print("\r\n")
 	
-	// Use a simple select/deselect loop instead of promptui's Select
-	// because we need multi-selection with toggling
-	selectedMap := make(map[string]bool)
-	for i, item := range items {
-		selectedMap[fmt.Sprintf("%d", i)] = item.Selected
-	}
+	keys := newKeyReader(os.Stdin)
+	defer keys.close()
 	
-	currentIndex := 0
+	resized := make(chan os.Signal, 1)
+	notifyResize(resized)
+	defer signal.Stop(resized)
 	
+	model := newPickerModel(items, destRepo, verbose)
+	waiting := false
 	for {
-		// Clear screen and redraw (more compatible)
-		fmt.Print("\033[2J\033[H")
-		fmt.Printf("Current state → Desired state for %s:\n\n", destRepo)
+		model.setTermSize(terminalSize())
+		fmt.Print(strings.ReplaceAll(model.view(), "\n", "\r\n"))
 		
-		// Show separator after dest-only labels
-		lastDestOnly := -1
-		for i, item := range items {
-			if item.IsDestOnly {
-				lastDestOnly = i
-			}
+		// Wait for keys, redrawing if the terminal is resized
+		if !waiting {
+			keys.request()
+			waiting = true
 		}
-		
-		// Display all items
-		for i, item := range items {
-			if lastDestOnly >= 0 && i == lastDestOnly+1 {
-				fmt.Println("  ────────────────────────────────────────────────")
-			}
-			
-			selected := selectedMap[fmt.Sprintf("%d", i)]
-			checkbox := "[ ]"
-			if selected {
-				checkbox = "[✓]"
-			}
-			
-			cursor := "  "
-			if i == currentIndex {
-				cursor = "> "
-			}
-			
-			label := FormatLabel(item.Label, verbose)
-			if item.IsDestOnly {
-				label += " (dest only)"
-				if !selected {
-					label += " [WARN] will be deleted"
+		select {
+		case <-resized:
+			continue
+		case events := <-keys.events:
+			waiting = false
+			for _, key := range events {
+				model.update(key)
+				if model.done {
+					break
 				}
 			}
-			
-			fmt.Printf("%s%s %s\n", cursor, checkbox, label)
 		}
 		
-		selectedCount := 0
-		for _, sel := range selectedMap {
-			if sel {
-				selectedCount++
-			}
-		}
-		
-		fmt.Printf("\n  Space: toggle  a: toggle all  ↑/↓: navigate  Enter: confirm  q: quit\n")
-		fmt.Printf("\n  %d selected\n", selectedCount)
-		
-		// Get single keypress
-		key := getKeypress()
-		
-		switch key {
-		case 'q', 'Q':
+		if model.cancelled {
 			return nil, fmt.Errorf("cancelled")
-		case '\n', '\r': // Enter
-			var selected []Label
-			for i, item := range items {
-				if selectedMap[fmt.Sprintf("%d", i)] {
-					selected = append(selected, item.Label)
-				}
-			}
-			return selected, nil
-		case ' ': // Space
-			key := fmt.Sprintf("%d", currentIndex)
-			selectedMap[key] = !selectedMap[key]
-		case 'a', 'A': // Toggle all
-			// Count how many are currently selected
-			allSelected := true
-			for _, v := range selectedMap {
-				if !v {
-					allSelected = false
-					break
-				}
-			}
-			// Toggle all to opposite state
-			for k := range selectedMap {
-				selectedMap[k] = !allSelected
-			}
-		case '\x1b': // Escape sequence
-			// Read the rest of the escape sequence
-			getKeypress() // [
-			switch getKeypress() {
-			case 'A': // Up arrow
-				if currentIndex > 0 {
-					currentIndex--
-				}
-			case 'B': // Down arrow
-				if currentIndex < len(items)-1 {
-					currentIndex++
-				}
-			}
+		}
+		if model.done {
+			return model.items, nil
 		}
 	}
 }
@@ -131,14 +74,25 @@ func ShowPicker(sourceLabels, destLabels []Label, destRepo string, verbose bool)
 func buildPickerItems(sourceLabels, destLabels []Label) []PickerItem {
 	items := []PickerItem{}
 	destMap := make(map[string]Label)
+	sourceMap := make(map[string]Label)
 	
 	// Index destination labels by lowercase name
 	for _, label := range destLabels {
 		destMap[strings.ToLower(label.Name)] = label
 	}
 	
+	// Index source labels by lowercase name
+	for _, label := range sourceLabels {
+		sourceMap[strings.ToLower(label.Name)] = label
+	}
+	
 	// Add destination-only labels first
 	for _, label := range destLabels {
+		// Labels that differ from the source are listed with the source labels
+		if source, exists := sourceMap[strings.ToLower(label.Name)]; exists && labelsDiffer(source, label) {
+			continue
+		}
+		
 		items = append(items, PickerItem{
 			Label:      label,
 			Selected:   true,
@@ -146,6 +100,21 @@ func buildPickerItems(sourceLabels, destLabels []Label) []PickerItem {
 		})
 	}
 	
+	// Add labels that exist in both repos with a different color or description
+	for _, label := range sourceLabels {
+		dest, exists := destMap[strings.ToLower(label.Name)]
+		if !exists || !labelsDiffer(label, dest) {
+			continue
+		}
+		
+		items = append(items, PickerItem{
+			Label:    label,
+			Current:  dest,
+			Selected: true,
+			Differs:  true,
+		})
+	}
+	
 	// Add source labels
 	for _, label := range sourceLabels {
 		// Skip if already exists in destination
@@ -163,38 +132,54 @@ func buildPickerItems(sourceLabels, destLabels []Label) []PickerItem {
 	return items
 }
 
+// Reports whether two labels with the same name have a different color or description
+func labelsDiffer(a, b Label) bool {
+	return normalizeColor(a.Color) != normalizeColor(b.Color) || a.Description != b.Description
+}
+
+// Returns the display group of an item: dest only, differs or rename, or source
+func itemGroup(item PickerItem) int {
+	switch {
+	case item.IsDestOnly:
+		return 0
+	case item.Differs, item.Rename:
+		return 1
+	default:
+		return 2
+	}
+}
+
 // Shows final confirmation and applies changes
-func ConfirmAndApply(selectedLabels, destLabels []Label, destRepo string) error {
-	summary := calculateActions(selectedLabels, destLabels)
+func ConfirmAndApply(items []PickerItem, destLabels []Label, destRepo string) error {
+	summary := summarize(items, destLabels)
 	
 	// Show final state
 	fmt.Printf("\nFinal state for %s:\n", destRepo)
-	for _, label := range selectedLabels {
+	for _, label := range selectedLabels(items) {
 		fmt.Printf("  ✓ %s\n", FormatLabel(label, false))
 	}
 	
-	// Show actions
-	fmt.Printf("\nActions:\n")
-	if len(summary.ToCreate) > 0 {
-		if len(summary.ToCreate) == 1 {
-			fmt.Printf("  • Create 1 label\n")
-		} else {
-			fmt.Printf("  • Create %d labels\n", len(summary.ToCreate))
-		}
-	}
-	if len(summary.ToDelete) > 0 {
-		if len(summary.ToDelete) == 1 {
-			fmt.Printf("  • Delete 1 label (%s)\n", summary.ToDelete[0].Name)
-		} else {
-			fmt.Printf("  • Delete %d labels\n", len(summary.ToDelete))
+	printActions(summary)
+	
+	// Warn about deleting labels that are still on issues or PRs
+	usage := make(map[string]LabelUses)
+	for _, item := range items {
+		if item.IsDestOnly && !item.Uses.Unknown {
+			usage[strings.ToLower(item.Label.Name)] = item.Uses
 		}
 	}
-	if len(summary.ToKeep) > 0 {
-		if len(summary.ToKeep) == 1 {
-			fmt.Printf("  • Keep 1 existing label\n")
-		} else {
-			fmt.Printf("  • Keep %d existing labels\n", len(summary.ToKeep))
+	inUse := inUseDeletes(summary, usage)
+	
+	// List every deletion right above the prompt
+	printDeletions(os.Stdout, summary, usage)
+	
+	// Destructive plans need the repo name typed, which also covers
+	// deleting labels that are in use
+	if needsTypedConfirm(summary) {
+		if err := confirmRepoName(destRepo, len(summary.ToDelete)); err != nil {
+			return err
 		}
+		return applyChanges(summary, destRepo)
 	}
 	
 	// Confirm
@@ -209,22 +194,67 @@ func ConfirmAndApply(selectedLabels, destLabels []Label, destRepo string) error
 		return fmt.Errorf("cancelled")
 	}
 	
+	// In-use deletes need a second, explicit confirmation
+	if len(inUse) > 0 {
+		prompt := promptui.Prompt{
+			Label:     fmt.Sprintf("Delete %d labels that are in use", len(inUse)),
+			IsConfirm: true,
+			Default:   "n",
+		}
+		if _, err := prompt.Run(); err != nil {
+			return fmt.Errorf("cancelled")
+		}
+	}
+	
 	// Apply changes
 	return applyChanges(summary, destRepo)
 }
 
-// Gets a single keypress from the terminal
-func getKeypress() byte {
-	// Put terminal in raw mode
-	oldState, err := term.MakeRaw(int(os.Stdin.Fd()))
-	if err != nil {
-		return 0
+// Prints the actions a summary will perform
+func printActions(summary ActionSummary) {
+	fmt.Printf("\nActions:\n")
+	if len(summary.ToCreate) > 0 {
+		if len(summary.ToCreate) == 1 {
+			fmt.Printf("  • Create 1 label\n")
+		} else {
+			fmt.Printf("  • Create %d labels\n", len(summary.ToCreate))
+		}
+	}
+	if len(summary.ToUpdate) > 0 {
+		if len(summary.ToUpdate) == 1 {
+			fmt.Printf("  • Update 1 label (%s)\n", summary.ToUpdate[0].Name)
+		} else {
+			fmt.Printf("  • Update %d labels\n", len(summary.ToUpdate))
+		}
+	}
+	if len(summary.ToRename) > 0 {
+		if len(summary.ToRename) == 1 {
+			fmt.Printf("  • Rename 1 label (%s → %s)\n", summary.ToRename[0].From, summary.ToRename[0].To.Name)
+		} else {
+			fmt.Printf("  • Rename %d labels\n", len(summary.ToRename))
+		}
+	}
+	if len(summary.ToMerge) > 0 {
+		if len(summary.ToMerge) == 1 {
+			fmt.Printf("  • Merge 1 label (%s → %s)\n", summary.ToMerge[0].From, summary.ToMerge[0].Into.Name)
+		} else {
+			fmt.Printf("  • Merge %d labels\n", len(summary.ToMerge))
+		}
+	}
+	if len(summary.ToDelete) > 0 {
+		if len(summary.ToDelete) == 1 {
+			fmt.Printf("  • Delete 1 label (%s)\n", summary.ToDelete[0].Name)
+		} else {
+			fmt.Printf("  • Delete %d labels\n", len(summary.ToDelete))
+		}
+	}
+	if len(summary.ToKeep) > 0 {
+		if len(summary.ToKeep) == 1 {
+			fmt.Printf("  • Keep 1 existing label\n")
+		} else {
+			fmt.Printf("  • Keep %d existing labels\n", len(summary.ToKeep))
+		}
 	}
-	defer func() { _ = term.Restore(int(os.Stdin.Fd()), oldState) }()
-
-	b := make([]byte, 1)
-	_, _ = os.Stdin.Read(b)
-	return b[0]
 }
 
 // Calculates what actions need to be taken
@@ -244,15 +274,20 @@ func calculateActions(selectedLabels, destLabels []Label) ActionSummary {
 	
 	summary := ActionSummary{
 		ToCreate: []Label{},
+		ToUpdate: []Label{},
 		ToDelete: []Label{},
 		ToKeep:   []Label{},
 	}
 	
-	// Find labels to create (in selected but not in dest)
+	// Find labels to create (in selected but not in dest) or update (in both but different)
 	for _, label := range selectedLabels {
-		if _, exists := destMap[strings.ToLower(label.Name)]; !exists {
+		dest, exists := destMap[strings.ToLower(label.Name)]
+		switch {
+		case !exists:
 			summary.ToCreate = append(summary.ToCreate, label)
-		} else {
+		case labelsDiffer(label, dest):
+			summary.ToUpdate = append(summary.ToUpdate, label)
+		default:
 			summary.ToKeep = append(summary.ToKeep, label)
 		}
 	}
@@ -269,50 +304,120 @@ func calculateActions(selectedLabels, destLabels []Label) ActionSummary {
 
 // Applies the changes to the destination repository
 func applyChanges(summary ActionSummary, destRepo string) error {
-	totalOps := len(summary.ToDelete) + len(summary.ToCreate)
-	currentOp := 0
+	return applyChangesTo(os.Stdout, summary, destRepo)
+}
+
+// Applies the changes, writing progress to out
+func applyChangesTo(out io.Writer, summary ActionSummary, destRepo string) error {
+	// However the summary was made, protected labels are never changed
+	if err := checkProtected(summary); err != nil {
+		return err
+	}
+	if err := checkNoDelete(summary); err != nil {
+		return err
+	}
+
+	ops := planOperations(summary)
 	
-	// Delete labels
-	for _, label := range summary.ToDelete {
-		currentOp++
-		fmt.Printf("[%d/%d] Deleting %s...\n", currentOp, totalOps, label.Name)
-		if err := DeleteLabel(destRepo, label.Name); err != nil {
-			return fmt.Errorf("failed to delete label %s: %v", label.Name, err)
-		}
+	// Record what the destination looks like, so the apply can be undone
+	snapshotPath, err := takeSnapshot(destRepo, summary)
+	if err != nil {
+		return fmt.Errorf("failed to snapshot %s, nothing was changed: %v", destRepo, err)
 	}
+	fmt.Fprintf(out, "Saved snapshot to %s\n", snapshotPath)
 	
-	// Create labels
-	for _, label := range summary.ToCreate {
-		currentOp++
-		fmt.Printf("[%d/%d] Creating %s...\n", currentOp, totalOps, label.Name)
-		if err := CreateLabel(destRepo, label); err != nil {
-			return fmt.Errorf("failed to create label %s: %v", label.Name, err)
-		}
+	// Deletes run first, then renames, updates and creates, and merges
+	// last so their target labels exist. Progress goes to a journal so a
+	// failed or interrupted apply can be resumed.
+	journal, err := newJournal(destRepo, snapshotPath, ops)
+	if err != nil {
+		return fmt.Errorf("failed to start journal, nothing was changed: %v", err)
+	}
+	if err := runJournal(out, journal); err != nil {
+		return err
 	}
 	
-	fmt.Printf("\nDone! ")
+	fmt.Fprintf(out, "\nDone! ")
 	if len(summary.ToCreate) > 0 {
 		if len(summary.ToCreate) == 1 {
-			fmt.Printf("Created 1 label. ")
+			fmt.Fprintf(out, "Created 1 label. ")
+		} else {
+			fmt.Fprintf(out, "Created %d labels. ", len(summary.ToCreate))
+		}
+	}
+	if len(summary.ToUpdate) > 0 {
+		if len(summary.ToUpdate) == 1 {
+			fmt.Fprintf(out, "Updated 1 label. ")
+		} else {
+			fmt.Fprintf(out, "Updated %d labels. ", len(summary.ToUpdate))
+		}
+	}
+	if len(summary.ToRename) > 0 {
+		if len(summary.ToRename) == 1 {
+			fmt.Fprintf(out, "Renamed 1 label. ")
+		} else {
+			fmt.Fprintf(out, "Renamed %d labels. ", len(summary.ToRename))
+		}
+	}
+	if len(summary.ToMerge) > 0 {
+		if len(summary.ToMerge) == 1 {
+			fmt.Fprintf(out, "Merged 1 label. ")
 		} else {
-			fmt.Printf("Created %d labels. ", len(summary.ToCreate))
+			fmt.Fprintf(out, "Merged %d labels. ", len(summary.ToMerge))
 		}
 	}
 	if len(summary.ToDelete) > 0 {
 		if len(summary.ToDelete) == 1 {
-			fmt.Printf("Deleted 1 label. ")
+			fmt.Fprintf(out, "Deleted 1 label. ")
 		} else {
-			fmt.Printf("Deleted %d labels. ", len(summary.ToDelete))
+			fmt.Fprintf(out, "Deleted %d labels. ", len(summary.ToDelete))
 		}
 	}
 	if len(summary.ToKeep) > 0 {
 		if len(summary.ToKeep) == 1 {
-			fmt.Printf("Kept 1 existing label.")
+			fmt.Fprintf(out, "Kept 1 existing label.")
 		} else {
-			fmt.Printf("Kept %d existing labels.", len(summary.ToKeep))
+			fmt.Fprintf(out, "Kept %d existing labels.", len(summary.ToKeep))
+		}
+	}
+	fmt.Fprintln(out)
+	
+	return nil
+}
+
+// Performs a single operation against the destination repository
+func applyOperation(out io.Writer, destRepo string, op Operation, currentOp, totalOps int) error {
+	label := op.Label
+	
+	switch op.Action {
+	case "delete":
+		fmt.Fprintf(out, "[%d/%d] Deleting %s...\n", currentOp, totalOps, label.Name)
+		if err := DeleteLabel(destRepo, label.Name); err != nil {
+			return fmt.Errorf("failed to delete label %s: %v", label.Name, err)
+		}
+	case "update":
+		fmt.Fprintf(out, "[%d/%d] Updating %s...\n", currentOp, totalOps, label.Name)
+		if err := UpdateLabel(destRepo, label); err != nil {
+			return fmt.Errorf("failed to update label %s: %v", label.Name, err)
 		}
+	case "rename":
+		fmt.Fprintf(out, "[%d/%d] Renaming %s to %s...\n", currentOp, totalOps, op.From, label.Name)
+		if err := RenameLabel(destRepo, op.From, label); err != nil {
+			return fmt.Errorf("failed to rename label %s: %v", op.From, err)
+		}
+	case "create":
+		fmt.Fprintf(out, "[%d/%d] Creating %s...\n", currentOp, totalOps, label.Name)
+		if err := CreateLabel(destRepo, label); err != nil {
+			return fmt.Errorf("failed to create label %s: %v", label.Name, err)
+		}
+	case "merge":
+		fmt.Fprintf(out, "[%d/%d] Merging %s into %s...\n", currentOp, totalOps, op.From, label.Name)
+		if err := mergeLabel(out, destRepo, op.From, label); err != nil {
+			return fmt.Errorf("failed to merge label %s into %s: %v", op.From, label.Name, err)
+		}
+	default:
+		return fmt.Errorf("unknown action %q for label %s", op.Action, label.Name)
 	}
-	fmt.Println()
 	
 	return nil
-}
\ No newline at end of file
+}
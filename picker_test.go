@@ -18,17 +18,19 @@ func TestBuildPickerItems(t *testing.T) {
 
 	items := buildPickerItems(sourceLabels, destLabels)
 
-	// Should have 4 items total: 2 dest-only + 2 source-only
+	// Should have 4 items total: 1 dest-only + 1 differs + 2 source-only
 	if len(items) != 4 {
-		t.Errorf("Expected 4 items, got %d", len(items))
+		t.Fatalf("Expected 4 items, got %d", len(items))
 	}
 
 	// First items should be dest-only
-	if !items[0].IsDestOnly || items[0].Label.Name != "bug" {
-		t.Error("First item should be dest-only 'bug'")
+	if !items[0].IsDestOnly || items[0].Label.Name != "wontfix" {
+		t.Error("First item should be dest-only 'wontfix'")
 	}
-	if !items[1].IsDestOnly || items[1].Label.Name != "wontfix" {
-		t.Error("Second item should be dest-only 'wontfix'")
+
+	// Labels in both repos with a different color come next
+	if !items[1].Differs || items[1].Label.Color != "#d73a4a" || items[1].Current.Color != "#ff0000" {
+		t.Error("Second item should be 'bug' differing from #ff0000 to #d73a4a")
 	}
 
 	// Remaining items should be source labels (excluding duplicate 'bug')
@@ -47,6 +49,26 @@ func TestBuildPickerItems(t *testing.T) {
 	}
 }
 
+func TestBuildPickerItemsIdentical(t *testing.T) {
+	sourceLabels := []Label{
+		{Name: "Bug", Color: "#D73A4A", Description: "Something isn't working"},
+	}
+
+	destLabels := []Label{
+		{Name: "bug", Color: "d73a4a", Description: "Something isn't working"},
+	}
+
+	items := buildPickerItems(sourceLabels, destLabels)
+
+	// Identical labels only show up once, as the destination version
+	if len(items) != 1 {
+		t.Fatalf("Expected 1 item, got %d", len(items))
+	}
+	if !items[0].IsDestOnly || items[0].Differs {
+		t.Error("Identical label should be listed as a destination label")
+	}
+}
+
 func TestCalculateActions(t *testing.T) {
 	selectedLabels := []Label{
 		{Name: "bug", Color: "#d73a4a"},
@@ -54,7 +76,7 @@ func TestCalculateActions(t *testing.T) {
 	}
 
 	destLabels := []Label{
-		{Name: "bug", Color: "#ff0000"},
+		{Name: "bug", Color: "d73a4a"},
 		{Name: "wontfix", Color: "#ffffff"},
 		{Name: "documentation", Color: "#0075ca"},
 	}
@@ -84,8 +106,8 @@ func TestCalculateActionsCaseInsensitive(t *testing.T) {
 	}
 
 	destLabels := []Label{
-		{Name: "bug", Color: "#ff0000"},
-		{Name: "feature", Color: "#00ff00"},
+		{Name: "bug", Color: "#d73a4a"},
+		{Name: "feature", Color: "#A2EEEF"},
 	}
 
 	summary := calculateActions(selectedLabels, destLabels)
@@ -102,4 +124,27 @@ func TestCalculateActionsCaseInsensitive(t *testing.T) {
 	if len(summary.ToKeep) != 2 {
 		t.Errorf("Expected to keep 2 labels, got %d", len(summary.ToKeep))
 	}
-}
\ No newline at end of file
+}
+
+func TestCalculateActionsUpdate(t *testing.T) {
+	selectedLabels := []Label{
+		{Name: "bug", Color: "#d73a4a", Description: "Something isn't working"},
+		{Name: "feature", Color: "#a2eeef"},
+	}
+
+	destLabels := []Label{
+		{Name: "bug", Color: "#ff0000", Description: "A bug"},
+		{Name: "feature", Color: "#a2eeef", Description: "New feature"},
+	}
+
+	summary := calculateActions(selectedLabels, destLabels)
+
+	// Both labels differ from the destination, so both are updated
+	if len(summary.ToUpdate) != 2 {
+		t.Errorf("Expected to update 2 labels, got %v", summary.ToUpdate)
+	}
+
+	if len(summary.ToCreate) != 0 || len(summary.ToDelete) != 0 || len(summary.ToKeep) != 0 {
+		t.Errorf("Expected only updates, got %+v", summary)
+	}
+}
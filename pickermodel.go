@@ -0,0 +1,532 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// pickerModel is the state of the interactive picker. update applies a
+// key to it and view draws it, so tests can drive the picker with a
+// script of keys instead of a terminal.
+type pickerModel struct {
+	items    []PickerItem
+	destRepo string
+	verbose  bool
+	width    int // Terminal size, 0 if unknown
+	height   int
+
+	visible   []int // Indexes of the items that match the filter
+	cursor    int   // Row in visible
+	top       int   // First row drawn
+	end       int   // Row after the last one drawn
+	filter    string
+	filtering bool      // Typing the filter
+	merging   bool      // Typing the label to merge into
+	input     string    // Merge target typed so far
+	editing   bool      // Editing the label under the cursor
+	edit      [3]string // Name, color and description being edited
+	editField int       // Which of them is being typed
+	message   string
+
+	done      bool
+	cancelled bool
+}
+
+func newPickerModel(items []PickerItem, destRepo string, verbose bool) *pickerModel {
+	m := &pickerModel{
+		items:    append([]PickerItem{}, items...),
+		destRepo: destRepo,
+		verbose:  verbose,
+	}
+	m.visible = filterItems(m.items, "")
+	m.fit()
+	return m
+}
+
+// Sets the terminal size, keeping the cursor in view
+func (m *pickerModel) setTermSize(width, height int) {
+	m.width, m.height = width, height
+	m.fit()
+}
+
+// Returns the index of the item under the cursor
+func (m *pickerModel) current() (int, bool) {
+	if len(m.visible) == 0 {
+		return 0, false
+	}
+	return m.visible[m.cursor], true
+}
+
+// Applies a keypress
+func (m *pickerModel) update(key keyEvent) {
+	if key.Kind == keyCtrlC {
+		m.done, m.cancelled = true, true
+		return
+	}
+
+	m.message = ""
+	switch {
+	case m.editing:
+		m.updateEdit(key)
+	case m.merging:
+		m.updateMerge(key)
+	case m.filtering:
+		m.updateFilter(key)
+	default:
+		m.updateList(key)
+	}
+	m.fit()
+}
+
+// Handles keys while moving through the list
+func (m *pickerModel) updateList(key keyEvent) {
+	page := max(m.end-m.top, 1)
+	last := max(len(m.visible)-1, 0)
+
+	switch key.Kind {
+	case keyEnter:
+		m.done = true
+	case keyEsc:
+		m.setFilter("")
+	case keyUp:
+		m.cursor = max(m.cursor-1, 0)
+	case keyDown:
+		m.cursor = min(m.cursor+1, last)
+	case keyPgUp:
+		m.cursor = max(m.cursor-page, 0)
+	case keyPgDn:
+		m.cursor = min(m.cursor+page, last)
+	case keyHome:
+		m.cursor = 0
+	case keyEnd:
+		m.cursor = last
+	case keyRune:
+		switch key.Rune {
+		case 'q', 'Q':
+			m.done, m.cancelled = true, true
+		case '/': // Filter by name and description
+			m.filtering = true
+		case ' ':
+			if i, ok := m.current(); ok {
+				if message := lockedMessage(m.items[i]); message != "" {
+					m.message = message
+					break
+				}
+				m.items[i].Selected = !m.items[i].Selected
+			}
+		case 'a', 'A': // Toggle all visible items, leaving locked ones alone
+			allSelected := true
+			for _, i := range m.visible {
+				if lockedMessage(m.items[i]) == "" && !m.items[i].Selected {
+					allSelected = false
+					break
+				}
+			}
+			for _, i := range m.visible {
+				if lockedMessage(m.items[i]) == "" {
+					m.items[i].Selected = !allSelected
+				}
+			}
+		case 'e', 'E': // Edit the label before it's applied
+			i, ok := m.current()
+			if !ok {
+				break
+			}
+			if message := lockedMessage(m.items[i]); message != "" {
+				m.message = message
+				break
+			}
+			label := m.items[i].Label
+			m.editing, m.editField = true, 0
+			m.edit = [3]string{label.Name, label.Color, label.Description}
+		case 'm', 'M': // Merge a destination label into another one
+			i, ok := m.current()
+			if !ok {
+				break
+			}
+			if !m.items[i].IsDestOnly {
+				m.message = "Only destination labels can be merged into another label"
+				break
+			}
+			if message := lockedMessage(m.items[i]); message != "" {
+				m.message = message
+				break
+			}
+			m.merging, m.input = true, ""
+		}
+	}
+}
+
+// Handles keys while typing the filter. The list narrows as it's typed.
+func (m *pickerModel) updateFilter(key keyEvent) {
+	switch key.Kind {
+	case keyEnter:
+		m.filtering = false
+	case keyEsc:
+		m.filtering = false
+		m.setFilter("")
+	case keyBackspace:
+		if m.filter == "" {
+			m.filtering = false
+			break
+		}
+		m.setFilter(dropLastRune(m.filter))
+	case keyRune:
+		m.setFilter(m.filter + string(key.Rune))
+	case keyUp, keyDown, keyPgUp, keyPgDn, keyHome, keyEnd:
+		m.filtering = false
+		m.updateList(key)
+	}
+}
+
+// Handles keys while typing the label to merge into. An empty name or
+// Esc takes back a merge chosen earlier.
+func (m *pickerModel) updateMerge(key keyEvent) {
+	i, _ := m.current()
+	item := &m.items[i]
+
+	switch key.Kind {
+	case keyEsc:
+		m.merging = false
+		item.MergeInto = ""
+	case keyBackspace:
+		m.input = dropLastRune(m.input)
+	case keyRune:
+		m.input += string(key.Rune)
+	case keyEnter:
+		m.merging = false
+		if strings.TrimSpace(m.input) == "" {
+			item.MergeInto = ""
+			break
+		}
+		target, found := findMergeTarget(m.items, i, m.input)
+		if !found {
+			m.message = fmt.Sprintf("No label named %q to merge into", m.input)
+			break
+		}
+		item.MergeInto = target
+		item.Selected = false
+	}
+}
+
+// Editor fields, in the order Tab moves through them
+var editFields = [3]string{"Name", "Color", "Description"}
+
+// Handles keys while editing a label. The edit is checked as it's typed
+// and can only be saved once it's valid.
+func (m *pickerModel) updateEdit(key keyEvent) {
+	switch key.Kind {
+	case keyEsc:
+		m.editing = false
+	case keyTab, keyDown:
+		m.editField = (m.editField + 1) % len(m.edit)
+	case keyUp:
+		m.editField = (m.editField + len(m.edit) - 1) % len(m.edit)
+	case keyBackspace:
+		m.edit[m.editField] = dropLastRune(m.edit[m.editField])
+	case keyRune:
+		m.edit[m.editField] += string(key.Rune)
+	case keyEnter:
+		label, err := m.editedLabel()
+		if err != nil {
+			break
+		}
+		m.editing = false
+
+		i, _ := m.current()
+		item := &m.items[i]
+		if label != item.Label {
+			item.Label = label
+			item.Modified = true
+		}
+		item.Selected = true
+	}
+}
+
+// Returns the label being edited, validated and with its color normalized
+func (m *pickerModel) editedLabel() (Label, error) {
+	i, _ := m.current()
+	item := m.items[i]
+	label := Label{
+		Name:        strings.TrimSpace(m.edit[0]),
+		Color:       strings.TrimSpace(m.edit[1]),
+		Description: strings.TrimSpace(m.edit[2]),
+	}
+
+	if err := validateLabel(label); err != nil {
+		return label, err
+	}
+	label.Color, _ = validateColor(label.Color)
+
+	// Labels already in the destination are updated by name, so renaming
+	// them here would create a new label instead
+	destName := ""
+	switch {
+	case item.IsDestOnly:
+		destName = item.Label.Name
+	case item.Differs:
+		destName = item.Current.Name
+	}
+	if destName != "" && !strings.EqualFold(label.Name, destName) {
+		return label, fmt.Errorf("%s is already in %s, so its name can't change here (use renames: in a labels file)", destName, m.destRepo)
+	}
+	if item.Rename && strings.EqualFold(label.Name, item.Current.Name) {
+		return label, fmt.Errorf("%s is the label's current name", item.Current.Name)
+	}
+
+	for j, other := range m.items {
+		if j == i {
+			continue
+		}
+		if strings.EqualFold(other.Label.Name, label.Name) || ((other.Differs || other.Rename) && strings.EqualFold(other.Current.Name, label.Name)) {
+			return label, fmt.Errorf("there is already a label named %s", label.Name)
+		}
+	}
+	return label, nil
+}
+
+// Changes the filter and moves the cursor back to the top
+func (m *pickerModel) setFilter(filter string) {
+	m.filter = filter
+	m.visible = filterItems(m.items, filter)
+	m.cursor, m.top = 0, 0
+}
+
+// Removes the last character of s
+func dropLastRune(s string) string {
+	r := []rune(s)
+	if len(r) == 0 {
+		return s
+	}
+	return string(r[:len(r)-1])
+}
+
+// Draws the picker: the rows that fit, with markers for those scrolled
+// off screen, then the keys and the selection count. The frame has no
+// trailing newline, so a frame as tall as the terminal doesn't scroll it.
+func (m *pickerModel) view() string {
+	lines := m.headerLines()
+	if m.top > 0 {
+		lines = append(lines, fmt.Sprintf("  ↑ %d more", m.top))
+	}
+	for row := m.top; row < m.end; row++ {
+		lines = append(lines, m.rowLines(row)...)
+	}
+	if m.end < len(m.visible) {
+		lines = append(lines, fmt.Sprintf("  ↓ %d more", len(m.visible)-m.end))
+	}
+	lines = append(lines, m.footerLines()...)
+
+	return "\033[2J\033[H" + strings.Join(lines, "\n")
+}
+
+// Returns the lines above the list: the title and the filter
+func (m *pickerModel) headerLines() []string {
+	lines := []string{fmt.Sprintf("Current state → Desired state for %s:", m.destRepo), ""}
+	if m.filtering || m.filter != "" {
+		cursorMark := ""
+		if m.filtering {
+			cursorMark = "_"
+		}
+		lines = append(lines, fmt.Sprintf("  Filter: /%s%s  (showing %d of %d)", m.filter, cursorMark, len(m.visible), len(m.items)), "")
+	}
+	if len(m.visible) == 0 {
+		lines = append(lines, "  No labels match the filter")
+	}
+	return lines
+}
+
+// Returns the lines of a row, with a separator above it when it starts a
+// new group below the first row drawn
+func (m *pickerModel) rowLines(row int) []string {
+	var lines []string
+	item := m.items[m.visible[row]]
+	if row > m.top && itemGroup(m.items[m.visible[row-1]]) != itemGroup(item) {
+		lines = append(lines, "  ────────────────────────────────────────────────")
+	}
+
+	checkbox := "[ ]"
+	if item.Selected {
+		checkbox = "[✓]"
+	}
+	cursor := "  "
+	if row == m.cursor {
+		cursor = "> "
+	}
+	return append(lines, fmt.Sprintf("%s%s %s", cursor, checkbox, formatPickerItem(item, m.verbose)))
+}
+
+// Returns the lines below the list: the editor, the keys, the selection
+// count, any message and the merge prompt
+func (m *pickerModel) footerLines() []string {
+	var lines []string
+	if m.editing {
+		lines = append(lines, m.editorLines()...)
+	}
+
+	switch {
+	case m.editing:
+		lines = append(lines, "", "  Tab/↑/↓: next field  Enter: save  Esc: cancel")
+	case m.merging:
+		lines = append(lines, "", "  Enter: merge  Esc: cancel")
+	case m.filtering:
+		lines = append(lines, "", "  Type to filter  Backspace: delete  Enter: done  Esc: clear")
+	default:
+		lines = append(lines, "", "  Space: toggle  a: toggle all  /: filter  e: edit  m: merge into  ↑/↓ PgUp/PgDn Home/End: navigate  Enter: confirm  q: quit")
+	}
+
+	selectedCount := 0
+	for _, item := range m.items {
+		if item.Selected {
+			selectedCount++
+		}
+	}
+	lines = append(lines, "", fmt.Sprintf("  %d selected", selectedCount))
+
+	if m.message != "" {
+		lines = append(lines, "", "  "+m.message)
+	}
+	if m.merging {
+		i, _ := m.current()
+		lines = append(lines, "", fmt.Sprintf("  Merge %s into (empty to cancel): %s_", m.items[i].Label.Name, m.input))
+	}
+	return lines
+}
+
+// Returns the editor's lines: the fields and whether the edit is valid
+func (m *pickerModel) editorLines() []string {
+	i, _ := m.current()
+	lines := []string{"", fmt.Sprintf("  Edit %s:", m.items[i].Label.Name)}
+	for field, name := range editFields {
+		cursor, mark := "    ", ""
+		if field == m.editField {
+			cursor, mark = "  > ", "_"
+		}
+		lines = append(lines, fmt.Sprintf("%s%-12s %s%s", cursor, name+":", m.edit[field], mark))
+	}
+	if label, err := m.editedLabel(); err != nil {
+		lines = append(lines, fmt.Sprintf("  ✗ %v", err))
+	} else {
+		lines = append(lines, "  ✓ "+FormatLabel(label, true))
+	}
+	return lines
+}
+
+// Works out which rows fit on screen with the cursor among them. The
+// rest of the frame, separators, scroll markers and wrapped lines are
+// measured as drawn, so the title never scrolls off.
+func (m *pickerModel) fit() {
+	total := len(m.visible)
+	if m.height <= 0 || total == 0 {
+		m.top, m.end = 0, total
+		return
+	}
+
+	available := m.height - linesRows(m.headerLines(), m.width) - linesRows(m.footerLines(), m.width)
+	m.top = min(m.top, m.cursor)
+	for {
+		m.end = m.rowsFrom(m.top, available)
+		if m.cursor < m.end {
+			break
+		}
+		m.top++
+	}
+
+	// Fill the screen when the list got shorter
+	for m.top > 0 && m.end == total && m.rowsFrom(m.top-1, available) == total {
+		m.top--
+	}
+}
+
+// Returns the end of the rows from top that fit in the available
+// terminal rows, leaving room for the scroll markers. The first row is
+// always drawn, even if it doesn't fit.
+func (m *pickerModel) rowsFrom(top, available int) int {
+	total := len(m.visible)
+	saved := m.top
+	m.top = top // rowLines puts separators below m.top
+	defer func() { m.top = saved }()
+
+	used := 0
+	if top > 0 {
+		used++ // ↑ more
+	}
+	end := top
+	for end < total {
+		rows := linesRows(m.rowLines(end), m.width)
+		below := 0
+		if end+1 < total {
+			below = 1 // ↓ more
+		}
+		if end > top && used+rows+below > available {
+			break
+		}
+		used += rows
+		end++
+	}
+	return end
+}
+
+// Explains why a locked item can't be toggled, edited or merged, or
+// returns "" if it can
+func lockedMessage(item PickerItem) string {
+	name := item.Label.Name
+	if item.Differs || item.Rename {
+		name = item.Current.Name
+	}
+	switch {
+	case item.Protected:
+		return fmt.Sprintf("%s is protected and can't be changed", name)
+	case item.ReadOnly:
+		return fmt.Sprintf("%s is kept: deletes are off (--no-delete)", name)
+	}
+	return ""
+}
+
+// Describes an item's label and what will happen to it
+func formatPickerItem(item PickerItem, verbose bool) string {
+	label := FormatLabel(item.Label, verbose)
+	if item.IsDestOnly {
+		if item.Uses.inUse() {
+			label += fmt.Sprintf(" (dest only, %s)", formatUses(item.Uses))
+		} else {
+			label += " (dest only)"
+		}
+		if !item.Selected && item.MergeInto != "" {
+			label += fmt.Sprintf(" → merge into %s", item.MergeInto)
+		} else if !item.Selected {
+			label += " [WARN] will be deleted"
+		}
+	}
+	if item.Differs {
+		label = FormatLabelChange(item.Current, item.Label, verbose) + " (differs)"
+		if !item.Selected {
+			label += " [keep current]"
+		}
+	}
+	if item.Rename {
+		suffix := " (rename)"
+		if item.Suggested {
+			suffix = " (rename?)"
+		}
+		label = FormatLabelRename(item.Current, item.Label, verbose) + suffix
+		if !item.Selected {
+			label += " [keep both]"
+		}
+	}
+	if item.Modified {
+		label += " (modified)"
+	}
+	if item.Protected {
+		label += " [locked: protected]"
+	} else if item.ReadOnly {
+		label += " [locked: no delete]"
+	}
+	if item.Origin != "" {
+		label += fmt.Sprintf(" [from %s]", item.Origin)
+	}
+	if item.Conflict != "" {
+		label += fmt.Sprintf(" [WARN] conflict: %s", item.Conflict)
+	}
+	return label
+}
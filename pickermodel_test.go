@@ -0,0 +1,266 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// Feeds a script of raw terminal input to a picker model
+func pressKeys(m *pickerModel, input string) {
+	var decoder keyDecoder
+	for _, key := range decoder.decode([]byte(input)) {
+		m.update(key)
+	}
+}
+
+func testPickerItems() []PickerItem {
+	return []PickerItem{
+		{Label: Label{Name: "stale", Color: "ffffff"}, Selected: true, IsDestOnly: true},
+		{Label: Label{Name: "defect", Color: "ee0701"}, Selected: true, IsDestOnly: true},
+		{Label: Label{Name: "bug", Color: "d73a4a", Description: "Something isn't working"}, Selected: true},
+		{Label: Label{Name: "docs", Color: "0075ca", Description: "Improvements to documentation"}, Selected: true},
+		{Label: Label{Name: "good first issue", Color: "7057ff"}, Selected: true},
+	}
+}
+
+func TestPickerToggleAndConfirm(t *testing.T) {
+	m := newPickerModel(testPickerItems(), "owner/repo", false)
+
+	// Uncheck stale, move down twice, uncheck bug, confirm
+	pressKeys(m, " \x1b[B\x1b[B \r")
+
+	if !m.done || m.cancelled {
+		t.Fatalf("Expected the picker to be confirmed, got done=%v cancelled=%v", m.done, m.cancelled)
+	}
+	for i, want := range []bool{false, true, false, true, true} {
+		if m.items[i].Selected != want {
+			t.Errorf("%s selected = %v, want %v", m.items[i].Label.Name, m.items[i].Selected, want)
+		}
+	}
+}
+
+func TestPickerCancel(t *testing.T) {
+	for _, input := range []string{"q", "\x03"} {
+		items := testPickerItems()
+		m := newPickerModel(items, "owner/repo", false)
+		pressKeys(m, " "+input)
+
+		if !m.done || !m.cancelled {
+			t.Errorf("%q should cancel the picker", input)
+		}
+		if !items[0].Selected {
+			t.Errorf("%q: cancelling should leave the caller's items alone", input)
+		}
+	}
+}
+
+func TestPickerNavigation(t *testing.T) {
+	m := newPickerModel(testPickerItems(), "owner/repo", false)
+	m.setTermSize(200, 9) // Room for two rows below the title and above the keys
+
+	tests := []struct {
+		input  string
+		cursor int
+	}{
+		{"\x1b[A", 0},  // Up at the top stays put
+		{"\x1b[B", 1},  // Down
+		{"\x1b[6~", 3}, // PgDn moves by the rows shown
+		{"\x1b[F", 4},  // End
+		{"\x1b[B", 4},  // Down at the bottom stays put
+		{"\x1b[5~", 2}, // PgUp
+		{"\x1b[H", 0},  // Home
+	}
+
+	for _, tt := range tests {
+		pressKeys(m, tt.input)
+		if m.cursor != tt.cursor {
+			t.Errorf("After %q: cursor %d, want %d", tt.input, m.cursor, tt.cursor)
+		}
+		if m.cursor < m.top || m.cursor >= m.end {
+			t.Errorf("After %q: cursor %d outside the rows drawn, %d to %d", tt.input, m.cursor, m.top, m.end)
+		}
+	}
+	if m.top != 0 {
+		t.Errorf("Home should scroll back to the top, got top %d", m.top)
+	}
+}
+
+func TestPickerFilter(t *testing.T) {
+	m := newPickerModel(testPickerItems(), "owner/repo", false)
+
+	// Typing narrows the list as it goes
+	pressKeys(m, "/d")
+	if !m.filtering || len(m.visible) != 3 {
+		t.Fatalf("Filter d should show defect, docs and good first issue, got %v", m.visible)
+	}
+	pressKeys(m, "oc")
+	if len(m.visible) != 1 || m.items[m.visible[0]].Label.Name != "docs" {
+		t.Fatalf("Filter doc should show docs, got %v", m.visible)
+	}
+	if !strings.Contains(m.view(), "showing 1 of 5") {
+		t.Errorf("Expected a showing indicator, got:\n%s", m.view())
+	}
+
+	// Backspace widens it again; a then toggles only the filtered items
+	pressKeys(m, "\x7f\x7f\ra")
+	if m.filtering {
+		t.Error("Enter should stop typing the filter")
+	}
+	for i, want := range []bool{true, false, true, false, false} {
+		if m.items[i].Selected != want {
+			t.Errorf("%s selected = %v, want %v", m.items[i].Label.Name, m.items[i].Selected, want)
+		}
+	}
+
+	// Esc clears the filter
+	pressKeys(m, "\x1b")
+	if m.filter != "" || len(m.visible) != 5 {
+		t.Errorf("Esc should clear the filter, got %q showing %d", m.filter, len(m.visible))
+	}
+}
+
+func TestPickerFilterTypesKeys(t *testing.T) {
+	m := newPickerModel(testPickerItems(), "owner/repo", false)
+
+	// Keys that are commands in the list are text in the filter
+	pressKeys(m, "/q a")
+	if m.done || m.filter != "q a" {
+		t.Errorf("Expected the filter to be %q, got %q (done=%v)", "q a", m.filter, m.done)
+	}
+}
+
+func TestPickerMerge(t *testing.T) {
+	m := newPickerModel(testPickerItems(), "owner/repo", false)
+
+	pressKeys(m, "\x1b[Bm")
+	if !m.merging {
+		t.Fatal("m should ask for the label to merge into")
+	}
+	pressKeys(m, "BUG\r")
+	if m.items[1].MergeInto != "bug" || m.items[1].Selected {
+		t.Errorf("defect should merge into bug, got %+v", m.items[1])
+	}
+
+	// Unknown targets are reported
+	pressKeys(m, "mnope\r")
+	if !strings.Contains(m.message, `No label named "nope"`) {
+		t.Errorf("Expected a message about the unknown label, got %q", m.message)
+	}
+
+	// Esc takes the merge back
+	pressKeys(m, "m\x1b")
+	if m.merging || m.items[1].MergeInto != "" {
+		t.Errorf("Esc should cancel the merge, got %+v", m.items[1])
+	}
+
+	// Only destination labels can be merged
+	pressKeys(m, "\x1b[Bm")
+	if m.merging || m.message == "" {
+		t.Error("Merging a source label should be refused")
+	}
+}
+
+func TestPickerView(t *testing.T) {
+	m := newPickerModel(testPickerItems(), "owner/repo", false)
+	m.setTermSize(200, 10)
+	pressKeys(m, "\x1b[B\x1b[B ")
+
+	view := m.view()
+	for _, want := range []string{"owner/repo", "↑ 2 more", "4 selected"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("View should contain %q:\n%s", want, view)
+		}
+	}
+	if !strings.Contains(view, "> [ ] ") || !strings.Contains(view[strings.Index(view, "> [ ] "):], "bug") {
+		t.Errorf("The cursor should be on an unchecked bug:\n%s", view)
+	}
+	if strings.Contains(view, "stale") {
+		t.Errorf("Rows scrolled off screen should not be drawn:\n%s", view)
+	}
+}
+
+func TestPickerEdit(t *testing.T) {
+	m := newPickerModel(testPickerItems(), "owner/repo", false)
+
+	// Change the color and description of docs
+	pressKeys(m, "\x1b[B\x1b[B\x1b[Be\t")
+	if !m.editing || m.editField != 1 {
+		t.Fatalf("Expected to be editing the color, got editing=%v field=%d", m.editing, m.editField)
+	}
+	pressKeys(m, strings.Repeat("\x7f", 6)+"#00ff")
+	if !strings.Contains(m.view(), "✗ invalid color format") {
+		t.Errorf("A partial color should show an error:\n%s", m.view())
+	}
+
+	// Enter doesn't save an invalid label
+	pressKeys(m, "\r")
+	if !m.editing {
+		t.Fatal("Enter should not save an invalid label")
+	}
+
+	pressKeys(m, "00\tX\r")
+	if m.editing {
+		t.Fatal("Enter should save a valid label")
+	}
+	want := Label{Name: "docs", Color: "00ff00", Description: "Improvements to documentationX"}
+	if m.items[3].Label != want || !m.items[3].Modified {
+		t.Errorf("Edited item = %+v, want %+v and modified", m.items[3], want)
+	}
+	if !strings.Contains(m.view(), "(modified)") {
+		t.Errorf("Edited labels should be marked modified:\n%s", m.view())
+	}
+
+	// Esc leaves the label as it was
+	pressKeys(m, "e\x7f\x7f\x1b")
+	if m.items[3].Label != want {
+		t.Errorf("Esc should discard the edit, got %+v", m.items[3].Label)
+	}
+}
+
+func TestPickerEditNames(t *testing.T) {
+	tests := []struct {
+		name  string
+		keys  string
+		error string
+	}{
+		{"empty", "\x1b[B\x1b[Be\x7f\x7f\x7f", "cannot be empty"},
+		{"taken", "\x1b[B\x1b[Be\x7f\x7f\x7fdocs", "already a label named docs"},
+		{"destination label", "\x1b[Be\x7fx", "its name can't change"},
+	}
+
+	for _, tt := range tests {
+		m := newPickerModel(testPickerItems(), "owner/repo", false)
+		pressKeys(m, tt.keys)
+		if _, err := m.editedLabel(); err == nil || !strings.Contains(err.Error(), tt.error) {
+			t.Errorf("%s: expected an error containing %q, got %v", tt.name, tt.error, err)
+		}
+	}
+
+	// Source labels can be renamed before they are created
+	m := newPickerModel(testPickerItems(), "owner/repo", false)
+	pressKeys(m, "\x1b[B\x1b[Be\x7f\x7f\x7ftype: bug\r")
+	if m.items[2].Label.Name != "type: bug" {
+		t.Errorf("Expected bug to become type: bug, got %q", m.items[2].Label.Name)
+	}
+}
+
+func TestPickerEditIsApplied(t *testing.T) {
+	destLabels := []Label{{Name: "stale", Color: "ffffff"}}
+	items := []PickerItem{
+		{Label: destLabels[0], Selected: true, IsDestOnly: true},
+		{Label: Label{Name: "bug", Color: "d73a4a"}, Selected: true},
+	}
+	m := newPickerModel(items, "owner/repo", false)
+
+	// Recolor the destination label and the label being created
+	pressKeys(m, "e\t"+strings.Repeat("\x7f", 6)+"000000\r")
+	pressKeys(m, "\x1b[Be\t"+strings.Repeat("\x7f", 6)+"#EEEEEE\r")
+
+	summary := summarize(m.items, destLabels)
+	if len(summary.ToUpdate) != 1 || summary.ToUpdate[0].Color != "000000" {
+		t.Errorf("Expected stale to be updated to 000000, got %+v", summary.ToUpdate)
+	}
+	if len(summary.ToCreate) != 1 || summary.ToCreate[0].Color != "EEEEEE" {
+		t.Errorf("Expected bug to be created as EEEEEE, got %+v", summary.ToCreate)
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Operation is a single label mutation in the destination repo
+type Operation struct {
+	Action string `json:"action"`         // create, update, rename, merge or delete
+	From   string `json:"from,omitempty"` // Old name of a renamed or merged label
+	Label  Label  `json:"label"`
+}
+
+// Plan is the exact set of operations gabel will perform on a destination repo
+type Plan struct {
+	Source     string      `json:"source"`
+	Dest       string      `json:"dest"`
+	DestDigest string      `json:"dest_digest"`
+	Operations []Operation `json:"operations"`
+	Protected  []string    `json:"protected,omitempty"` // Patterns apply must not touch
+	NoDelete   bool        `json:"no_delete,omitempty"`
+}
+
+// Builds a plan from an action summary
+func newPlan(summary ActionSummary, sourceRepo, destRepo string, destLabels []Label) Plan {
+	return Plan{
+		Source:     sourceRepo,
+		Dest:       destRepo,
+		DestDigest: labelsDigest(destLabels),
+		Operations: planOperations(summary),
+		Protected:  protect,
+		NoDelete:   noDelete,
+	}
+}
+
+// Lists the operations for a summary in the order applyChanges performs them
+func planOperations(summary ActionSummary) []Operation {
+	ops := []Operation{}
+	for _, label := range summary.ToDelete {
+		ops = append(ops, Operation{Action: "delete", Label: label})
+	}
+	for _, rename := range summary.ToRename {
+		ops = append(ops, Operation{Action: "rename", From: rename.From, Label: rename.To})
+	}
+	for _, label := range summary.ToUpdate {
+		ops = append(ops, Operation{Action: "update", Label: label})
+	}
+	for _, label := range summary.ToCreate {
+		ops = append(ops, Operation{Action: "create", Label: label})
+	}
+	for _, merge := range summary.ToMerge {
+		ops = append(ops, Operation{Action: "merge", From: merge.From, Label: merge.Into})
+	}
+	return ops
+}
+
+// Rebuilds the action summary a plan was made from
+func planSummary(plan Plan) (ActionSummary, error) {
+	summary := ActionSummary{
+		ToCreate: []Label{},
+		ToUpdate: []Label{},
+		ToRename: []LabelRename{},
+		ToMerge:  []LabelMerge{},
+		ToDelete: []Label{},
+		ToKeep:   []Label{},
+	}
+
+	for i, op := range plan.Operations {
+		switch op.Action {
+		case "create":
+			summary.ToCreate = append(summary.ToCreate, op.Label)
+		case "update":
+			summary.ToUpdate = append(summary.ToUpdate, op.Label)
+		case "rename":
+			if op.From == "" {
+				return summary, fmt.Errorf("operation %d: rename of %q has no old name", i+1, op.Label.Name)
+			}
+			summary.ToRename = append(summary.ToRename, LabelRename{From: op.From, To: op.Label})
+		case "merge":
+			if op.From == "" {
+				return summary, fmt.Errorf("operation %d: merge into %q has no old name", i+1, op.Label.Name)
+			}
+			summary.ToMerge = append(summary.ToMerge, LabelMerge{From: op.From, Into: op.Label})
+		case "delete":
+			summary.ToDelete = append(summary.ToDelete, op.Label)
+		default:
+			return summary, fmt.Errorf("operation %d: unknown action %q", i+1, op.Action)
+		}
+	}
+
+	return summary, nil
+}
+
+// Computes a stable fingerprint of a repo's labels
+func labelsDigest(labels []Label) string {
+	lines := make([]string, 0, len(labels))
+	for _, label := range labels {
+		lines = append(lines, fmt.Sprintf("%s\x00%s\x00%s", label.Name, normalizeColor(label.Color), label.Description))
+	}
+	sort.Strings(lines)
+
+	sum := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// Prints a plan as the list of API requests it will make
+func printPlan(w io.Writer, plan Plan) {
+	if len(plan.Operations) == 0 {
+		fmt.Fprintf(w, "No changes. %s is up to date.\n", plan.Dest)
+		return
+	}
+
+	if len(plan.Operations) == 1 {
+		fmt.Fprintf(w, "Plan for %s (1 operation):\n\n", plan.Dest)
+	} else {
+		fmt.Fprintf(w, "Plan for %s (%d operations):\n\n", plan.Dest, len(plan.Operations))
+	}
+
+	for _, op := range plan.Operations {
+		fmt.Fprintf(w, "  %s\n", describeOperation(plan.Dest, op))
+	}
+}
+
+// Describes the API request an operation makes
+func describeOperation(repo string, op Operation) string {
+	_, repo = splitRepo(repo)
+	labelPath := fmt.Sprintf("repos/%s/labels/%s", repo, url.PathEscape(op.Label.Name))
+	fields := fmt.Sprintf("color=%s description=%q", normalizeColor(op.Label.Color), op.Label.Description)
+
+	switch op.Action {
+	case "create":
+		return fmt.Sprintf("+ POST   repos/%s/labels  name=%q %s", repo, op.Label.Name, fields)
+	case "update":
+		return fmt.Sprintf("~ PATCH  %s  %s", labelPath, fields)
+	case "rename":
+		oldPath := fmt.Sprintf("repos/%s/labels/%s", repo, url.PathEscape(op.From))
+		return fmt.Sprintf("~ PATCH  %s  new_name=%q %s", oldPath, op.Label.Name, fields)
+	case "merge":
+		oldPath := fmt.Sprintf("repos/%s/labels/%s", repo, url.PathEscape(op.From))
+		return fmt.Sprintf("> MERGE  %s  into=%q (relabel every issue and PR, then DELETE)", oldPath, op.Label.Name)
+	case "delete":
+		return fmt.Sprintf("- DELETE %s", labelPath)
+	}
+	return fmt.Sprintf("? %s %s", op.Action, op.Label.Name)
+}
+
+// Writes a plan as indented JSON
+func writePlanJSON(w io.Writer, plan Plan) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(plan)
+}
+
+// Writes several plans as an indented JSON array
+func writePlansJSON(w io.Writer, plans []Plan) error {
+	if plans == nil {
+		plans = []Plan{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(plans)
+}
+
+// Saves a plan to a JSON file
+func savePlan(path string, plan Plan) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to save plan: %v", err)
+	}
+
+	if err := writePlanJSON(f, plan); err != nil {
+		_ = f.Close()
+		return err
+	}
+	// A write that fails late, like on a full disk, only shows up here
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to save plan: %v", err)
+	}
+	return nil
+}
+
+// Loads a plan saved with --save-plan
+func loadPlan(path string) (Plan, error) {
+	var plan Plan
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return plan, fmt.Errorf("failed to read plan: %v", err)
+	}
+
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return plan, fmt.Errorf("failed to parse plan %s: %v", path, err)
+	}
+
+	if !isValidRepo(plan.Dest) {
+		return plan, fmt.Errorf("plan %s has an invalid destination repo: %q", path, plan.Dest)
+	}
+
+	return plan, nil
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPlanOperationsOrder(t *testing.T) {
+	summary := ActionSummary{
+		ToCreate: []Label{{Name: "feature", Color: "a2eeef"}},
+		ToUpdate: []Label{{Name: "bug", Color: "d73a4a"}},
+		ToDelete: []Label{{Name: "wontfix", Color: "ffffff"}},
+		ToKeep:   []Label{{Name: "docs", Color: "0075ca"}},
+	}
+
+	ops := planOperations(summary)
+
+	want := []string{"delete wontfix", "update bug", "create feature"}
+	if len(ops) != len(want) {
+		t.Fatalf("Expected %d operations, got %d", len(want), len(ops))
+	}
+	for i, op := range ops {
+		if got := op.Action + " " + op.Label.Name; got != want[i] {
+			t.Errorf("Operation %d = %q, want %q", i, got, want[i])
+		}
+	}
+}
+
+func TestPlanRoundTrip(t *testing.T) {
+	destLabels := []Label{
+		{Name: "bug", Color: "#ff0000"},
+		{Name: "wontfix", Color: "#ffffff"},
+	}
+	summary := calculateActions([]Label{
+		{Name: "bug", Color: "#d73a4a", Description: "Something isn't working"},
+		{Name: "feature", Color: "#a2eeef"},
+	}, destLabels)
+
+	plan := newPlan(summary, "owner/source", "owner/dest", destLabels)
+
+	path := filepath.Join(t.TempDir(), "plan.json")
+	if err := savePlan(path, plan); err != nil {
+		t.Fatalf("savePlan() error = %v", err)
+	}
+
+	loaded, err := loadPlan(path)
+	if err != nil {
+		t.Fatalf("loadPlan() error = %v", err)
+	}
+
+	if loaded.DestDigest != labelsDigest(destLabels) {
+		t.Error("Loaded plan should keep the destination digest")
+	}
+
+	restored, err := planSummary(loaded)
+	if err != nil {
+		t.Fatalf("planSummary() error = %v", err)
+	}
+	if len(restored.ToCreate) != 1 || len(restored.ToUpdate) != 1 || len(restored.ToDelete) != 1 {
+		t.Errorf("Expected 1 create, 1 update and 1 delete, got %+v", restored)
+	}
+	if restored.ToUpdate[0].Description != "Something isn't working" {
+		t.Errorf("Update should keep the description, got %q", restored.ToUpdate[0].Description)
+	}
+}
+
+func TestPlanSummaryUnknownAction(t *testing.T) {
+	plan := Plan{Dest: "owner/dest", Operations: []Operation{{Action: "rename", Label: Label{Name: "bug"}}}}
+
+	if _, err := planSummary(plan); err == nil {
+		t.Error("Expected error for unknown action")
+	}
+}
+
+func TestLabelsDigest(t *testing.T) {
+	a := []Label{
+		{Name: "bug", Color: "#D73A4A"},
+		{Name: "docs", Color: "0075ca", Description: "Docs"},
+	}
+	b := []Label{
+		{Name: "docs", Color: "#0075CA", Description: "Docs"},
+		{Name: "bug", Color: "d73a4a"},
+	}
+
+	if labelsDigest(a) != labelsDigest(b) {
+		t.Error("Digest should not depend on label order or color formatting")
+	}
+
+	b[0].Description = "Documentation"
+	if labelsDigest(a) == labelsDigest(b) {
+		t.Error("Digest should change when a description changes")
+	}
+}
+
+func TestPrintPlan(t *testing.T) {
+	plan := Plan{
+		Dest: "owner/dest",
+		Operations: []Operation{
+			{Action: "delete", Label: Label{Name: "good first issue"}},
+			{Action: "update", Label: Label{Name: "bug", Color: "#d73a4a", Description: "Broken"}},
+			{Action: "create", Label: Label{Name: "feature", Color: "a2eeef"}},
+		},
+	}
+
+	var buf bytes.Buffer
+	printPlan(&buf, plan)
+	output := buf.String()
+
+	for _, want := range []string{
+		"3 operations",
+		"- DELETE repos/owner/dest/labels/good%20first%20issue",
+		`~ PATCH  repos/owner/dest/labels/bug  color=d73a4a description="Broken"`,
+		`+ POST   repos/owner/dest/labels  name="feature" color=a2eeef`,
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Plan output should contain %q, got:\n%s", want, output)
+		}
+	}
+
+	buf.Reset()
+	printPlan(&buf, Plan{Dest: "owner/dest"})
+	if !strings.Contains(buf.String(), "No changes") {
+		t.Error("Empty plan should say there are no changes")
+	}
+}
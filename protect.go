@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Reports whether a destination label matches a --protect or protected:
+// pattern, so gabel must never change or delete it
+func isProtected(name string) bool {
+	for _, pattern := range protect {
+		if globMatch(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Marks the items whose destination label is protected
+func markProtected(items []PickerItem) {
+	for i := range items {
+		item := &items[i]
+		switch {
+		case item.IsDestOnly:
+			item.Protected = isProtected(item.Label.Name)
+		case item.Differs, item.Rename:
+			item.Protected = isProtected(item.Current.Name)
+		}
+	}
+}
+
+// Leaves protected labels out of a summary, the way the picker and
+// --yes do: they keep their color and description and are never renamed,
+// merged or deleted. A protected label's rename target is created
+// alongside it instead.
+func dropProtected(summary ActionSummary, destLabels []Label) ActionSummary {
+	keep := func(name string) {
+		if i := indexOfLabel(destLabels, name); i >= 0 {
+			summary.ToKeep = append(summary.ToKeep, destLabels[i])
+		}
+	}
+
+	updates := []Label{}
+	for _, label := range summary.ToUpdate {
+		if isProtected(label.Name) {
+			keep(label.Name)
+		} else {
+			updates = append(updates, label)
+		}
+	}
+	renames := []LabelRename{}
+	for _, rename := range summary.ToRename {
+		if isProtected(rename.From) {
+			keep(rename.From)
+			summary.ToCreate = append(summary.ToCreate, rename.To)
+		} else {
+			renames = append(renames, rename)
+		}
+	}
+	merges := []LabelMerge{}
+	for _, merge := range summary.ToMerge {
+		if isProtected(merge.From) {
+			keep(merge.From)
+		} else {
+			merges = append(merges, merge)
+		}
+	}
+	deletes := []Label{}
+	for _, label := range summary.ToDelete {
+		if isProtected(label.Name) {
+			keep(label.Name)
+		} else {
+			deletes = append(deletes, label)
+		}
+	}
+
+	summary.ToUpdate, summary.ToRename, summary.ToMerge, summary.ToDelete = updates, renames, merges, deletes
+	return summary
+}
+
+// Rejects a summary that would update, rename, merge or delete a
+// protected label, wherever the summary came from
+func checkProtected(summary ActionSummary) error {
+	var names []string
+	for _, label := range summary.ToUpdate {
+		if isProtected(label.Name) {
+			names = append(names, label.Name)
+		}
+	}
+	for _, rename := range summary.ToRename {
+		if isProtected(rename.From) {
+			names = append(names, rename.From)
+		}
+	}
+	for _, merge := range summary.ToMerge {
+		if isProtected(merge.From) {
+			names = append(names, merge.From)
+		}
+	}
+	for _, label := range summary.ToDelete {
+		if isProtected(label.Name) {
+			names = append(names, label.Name)
+		}
+	}
+
+	if len(names) > 0 {
+		return fmt.Errorf("refusing to change protected labels: %s", strings.Join(names, ", "))
+	}
+	return nil
+}
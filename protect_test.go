@@ -0,0 +1,126 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// Sets the protected patterns for one test
+func useProtect(t *testing.T, patterns ...string) {
+	t.Helper()
+	old := protect
+	protect = patterns
+	t.Cleanup(func() { protect = old })
+}
+
+func TestProtectedItemsKeepDestination(t *testing.T) {
+	useProtect(t, "release/*", "security")
+
+	source := Manifest{
+		Labels: []Label{
+			{Name: "security", Color: "d73a4a"},
+			{Name: "bug", Color: "d73a4a"},
+		},
+		Merges: map[string]string{"release/v1": "bug"},
+	}
+	destLabels := []Label{
+		{Name: "Security", Color: "ee0701"},
+		{Name: "release/v1", Color: "ededed"},
+		{Name: "stale", Color: "ffffff"},
+	}
+
+	oldPrune := prune
+	defer func() { prune = oldPrune }()
+	prune = true
+
+	items := prepareItems(source, destLabels)
+	protected := 0
+	for _, item := range items {
+		if item.Protected {
+			protected++
+		}
+	}
+	if protected != 2 {
+		t.Fatalf("Expected Security and release/v1 to be protected, got %+v", items)
+	}
+
+	summary := summarize(items, destLabels)
+	if len(summary.ToUpdate) != 0 || len(summary.ToMerge) != 0 {
+		t.Errorf("Protected labels should not be updated or merged: %+v", summary)
+	}
+	if len(summary.ToDelete) != 1 || summary.ToDelete[0].Name != "stale" {
+		t.Errorf("Only stale should be deleted, got %+v", summary.ToDelete)
+	}
+	if err := checkProtected(summary); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCheckProtected(t *testing.T) {
+	useProtect(t, "release/*")
+
+	summary := ActionSummary{
+		ToCreate: []Label{{Name: "release/v2", Color: "ededed"}},
+		ToUpdate: []Label{{Name: "release/v1", Color: "ededed"}},
+		ToRename: []LabelRename{{From: "release/old", To: Label{Name: "old"}}},
+		ToDelete: []Label{{Name: "stale"}},
+	}
+	err := checkProtected(summary)
+	if err == nil || err.Error() != "refusing to change protected labels: release/v1, release/old" {
+		t.Errorf("checkProtected() = %v", err)
+	}
+}
+
+func TestApplyRejectsProtected(t *testing.T) {
+	ms := useMemStore(t)
+	ms.labels["owner/repo"] = []Label{{Name: "security", Color: "d73a4a"}}
+	useProtect(t, "security")
+
+	// A hand-edited plan can still list a protected label
+	summary := ActionSummary{ToDelete: []Label{{Name: "security", Color: "d73a4a"}}}
+
+	var out strings.Builder
+	err := applyChangesTo(&out, summary, "owner/repo")
+	if err == nil || !strings.Contains(err.Error(), "protected labels: security") {
+		t.Fatalf("Expected the apply to be refused, got %v", err)
+	}
+	if len(ms.labels["owner/repo"]) != 1 {
+		t.Error("Nothing should have been changed")
+	}
+}
+
+func TestPickerProtectedItems(t *testing.T) {
+	items := testPickerItems()
+	items[0].Protected = true
+	m := newPickerModel(items, "owner/repo", false)
+
+	// Space on the locked item, then toggle all twice
+	pressKeys(m, " ")
+	if !m.items[0].Selected || !strings.Contains(m.message, "stale is protected") {
+		t.Errorf("Space should not toggle a protected item, message %q", m.message)
+	}
+	pressKeys(m, "a")
+	for i, item := range m.items {
+		if item.Selected != (i == 0) {
+			t.Errorf("After a, item %d selected = %v", i, item.Selected)
+		}
+	}
+	pressKeys(m, "a")
+	for i, item := range m.items {
+		if !item.Selected {
+			t.Errorf("After a again, item %d should be selected", i)
+		}
+	}
+
+	pressKeys(m, "e")
+	if m.editing {
+		t.Error("Protected items should not be editable")
+	}
+	pressKeys(m, "m")
+	if m.merging {
+		t.Error("Protected items should not be mergeable")
+	}
+	if !strings.Contains(formatPickerItem(m.items[0], false), "[locked: protected]") {
+		t.Error("Protected items should be shown as locked")
+	}
+}
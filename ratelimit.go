@@ -0,0 +1,56 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// How often a rate-limited request is retried, and the longest gabel
+// waits for a limit to reset before giving up
+const (
+	maxRetries       = 5
+	maxRateLimitWait = 10 * time.Minute
+)
+
+// Returns the exponential backoff before retry attempt n, counting
+// from 0: 1s, 2s, 4s and so on, up to a minute
+func backoffDelay(attempt int) time.Duration {
+	delay := time.Second << attempt
+	if delay > time.Minute || delay <= 0 {
+		return time.Minute
+	}
+	return delay
+}
+
+// Works out how long to wait before retrying a rate-limited request:
+// Retry-After if GitHub sent it, the reset time when no requests are
+// left, and exponential backoff otherwise
+func rateLimitDelay(header http.Header, attempt int, now time.Time) time.Duration {
+	if seconds, err := strconv.Atoi(header.Get("Retry-After")); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if delay, ok := resetDelay(header, now); ok {
+		return delay
+	}
+	return backoffDelay(attempt)
+}
+
+// Returns how long until the rate limit resets, if no requests are left
+func resetDelay(header http.Header, now time.Time) (time.Duration, bool) {
+	if header.Get("X-RateLimit-Remaining") != "0" {
+		return 0, false
+	}
+
+	reset, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	// A second of slack for clock differences
+	delay := time.Unix(reset, 0).Sub(now) + time.Second
+	if delay < 0 {
+		delay = 0
+	}
+	return delay, true
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRateLimitDelay(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		attempt int
+		want    time.Duration
+	}{
+		{"retry after", map[string]string{"Retry-After": "30"}, 0, 30 * time.Second},
+		{"reset", map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": "1700000120"}, 0, 121 * time.Second},
+		{"reset passed", map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": "1699999990"}, 0, 0},
+		{"backoff", nil, 0, time.Second},
+		{"backoff grows", nil, 3, 8 * time.Second},
+		{"backoff caps", nil, 10, time.Minute},
+	}
+
+	for _, tt := range tests {
+		header := http.Header{}
+		for k, v := range tt.headers {
+			header.Set(k, v)
+		}
+		if got := rateLimitDelay(header, tt.attempt, now); got != tt.want {
+			t.Errorf("%s: rateLimitDelay = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRESTStoreRetriesRateLimits(t *testing.T) {
+	calls := 0
+	s := newTestRESTStore(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			w.Header().Set("Retry-After", "2")
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = io.WriteString(w, `{"message": "You have exceeded a secondary rate limit"}`)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		_, _ = io.WriteString(w, `{}`)
+	})
+
+	var slept []time.Duration
+	s.sleep = func(d time.Duration) { slept = append(slept, d) }
+	now := time.Unix(1700000000, 0)
+	s.now = func() time.Time { return now }
+
+	if err := s.CreateLabel("owner/repo", Label{Name: "bug", Color: "d73a4a"}); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 {
+		t.Errorf("Expected 3 requests, got %d", calls)
+	}
+	if len(slept) != 2 || slept[0] != 2*time.Second || slept[1] != 2*time.Second {
+		t.Errorf("Expected two 2s waits from Retry-After, got %v", slept)
+	}
+}
+
+func TestRESTStoreGivesUpOnRateLimits(t *testing.T) {
+	calls := 0
+	s := newTestRESTStore(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = io.WriteString(w, `{"message": "You have exceeded a secondary rate limit"}`)
+	})
+
+	if err := s.CreateLabel("owner/repo", Label{Name: "bug", Color: "d73a4a"}); err == nil {
+		t.Fatal("Expected an error once the retries run out")
+	}
+	if calls != maxRetries+1 {
+		t.Errorf("Expected %d requests, got %d", maxRetries+1, calls)
+	}
+}
+
+func TestRESTStoreWaitsForReset(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	reset := now.Add(30 * time.Second)
+
+	s := newTestRESTStore(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+		_, _ = io.WriteString(w, `[]`)
+	})
+
+	var slept []time.Duration
+	s.sleep = func(d time.Duration) { slept = append(slept, d) }
+	s.now = func() time.Time { return now }
+
+	if _, err := s.FetchLabels("owner/repo"); err != nil {
+		t.Fatal(err)
+	}
+	if len(slept) != 0 {
+		t.Errorf("The first request shouldn't wait, got %v", slept)
+	}
+
+	// The last response used up the limit, so the next request waits for the reset
+	if _, err := s.FetchLabels("owner/repo"); err != nil {
+		t.Fatal(err)
+	}
+	if len(slept) != 1 || slept[0] != 31*time.Second {
+		t.Errorf("Expected a 31s wait for the reset, got %v", slept)
+	}
+
+	// A reset further off than gabel is willing to wait doesn't hold requests
+	reset = now.Add(time.Hour)
+	s.pauseUntil = time.Time{}
+	slept = nil
+	if _, err := s.FetchLabels("owner/repo"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.FetchLabels("owner/repo"); err != nil {
+		t.Fatal(err)
+	}
+	if len(slept) != 0 {
+		t.Errorf("Resets too far off shouldn't hold requests, got %v", slept)
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// Pairs destination-only labels with new source labels they were renamed
+// to. Explicit renames from the manifest are selected; pairs guessed from
+// an identical color and description are only suggested, so they start
+// unselected and must be confirmed in the picker.
+func pairRenames(items []PickerItem, sourceLabels []Label, renames map[string]string) []PickerItem {
+	sourceMap := make(map[string]bool)
+	for _, label := range sourceLabels {
+		sourceMap[strings.ToLower(label.Name)] = true
+	}
+
+	// Candidates: labels only in the destination, and labels only in the source
+	oldItems := make(map[string]int)
+	newItems := make(map[string]int)
+	for i, item := range items {
+		key := strings.ToLower(item.Label.Name)
+		switch {
+		case item.IsDestOnly && !sourceMap[key]:
+			oldItems[key] = i
+		case !item.IsDestOnly && !item.Differs:
+			newItems[key] = i
+		}
+	}
+
+	paired := make(map[int]bool)
+	var renameItems []PickerItem
+	pair := func(oldIndex, newIndex int, suggested bool) {
+		paired[oldIndex] = true
+		paired[newIndex] = true
+		renameItems = append(renameItems, PickerItem{
+			Label:     items[newIndex].Label,
+			Current:   items[oldIndex].Label,
+			Selected:  !suggested,
+			Rename:    true,
+			Suggested: suggested,
+		})
+	}
+
+	froms := make([]string, 0, len(renames))
+	for from := range renames {
+		froms = append(froms, from)
+	}
+	sort.Strings(froms)
+	for _, from := range froms {
+		oldIndex, hasOld := oldItems[strings.ToLower(from)]
+		newIndex, hasNew := newItems[strings.ToLower(renames[from])]
+		if hasOld && hasNew {
+			pair(oldIndex, newIndex, false)
+		}
+	}
+
+	// Suggest a rename only when the match is unambiguous both ways
+	for _, newIndex := range sortedIndexes(newItems) {
+		if paired[newIndex] {
+			continue
+		}
+		match := similarLabels(items, oldItems, items[newIndex].Label, paired)
+		if len(match) != 1 {
+			continue
+		}
+		reverse := similarLabels(items, newItems, items[match[0]].Label, paired)
+		if len(reverse) == 1 {
+			pair(match[0], newIndex, true)
+		}
+	}
+
+	if len(renameItems) == 0 {
+		return items
+	}
+
+	// Renames are listed with the other changes, before the new labels
+	result := []PickerItem{}
+	for i, item := range items {
+		if !paired[i] && itemGroup(item) < 2 {
+			result = append(result, item)
+		}
+	}
+	result = append(result, renameItems...)
+	for i, item := range items {
+		if !paired[i] && itemGroup(item) == 2 {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// Returns the unpaired candidates with the same color and description as label.
+// Labels without a description are never matched on color alone.
+func similarLabels(items []PickerItem, candidates map[string]int, label Label, paired map[int]bool) []int {
+	if label.Description == "" {
+		return nil
+	}
+
+	var matches []int
+	for _, i := range sortedIndexes(candidates) {
+		if !paired[i] && !labelsDiffer(items[i].Label, label) {
+			matches = append(matches, i)
+		}
+	}
+	return matches
+}
+
+// Returns the item indexes of a candidate map in picker order
+func sortedIndexes(candidates map[string]int) []int {
+	indexes := make([]int, 0, len(candidates))
+	for _, i := range candidates {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+	return indexes
+}
+
+// Returns the confirmed renames, old name → new name
+func itemRenames(items []PickerItem) map[string]string {
+	renames := make(map[string]string)
+	for _, item := range items {
+		if item.Rename && item.Selected {
+			renames[strings.ToLower(item.Current.Name)] = item.Label.Name
+		}
+	}
+	return renames
+}
+
+// Turns each delete and create that make up a confirmed rename into a
+// single rename, so the label stays on the destination's issues and PRs
+func applyRenames(summary ActionSummary, renames map[string]string) ActionSummary {
+	if len(renames) == 0 {
+		return summary
+	}
+
+	summary.ToRename = []LabelRename{}
+	for from, to := range renames {
+		deleteIndex := indexOfLabel(summary.ToDelete, from)
+		createIndex := indexOfLabel(summary.ToCreate, to)
+		if deleteIndex < 0 || createIndex < 0 {
+			continue
+		}
+
+		summary.ToRename = append(summary.ToRename, LabelRename{
+			From: summary.ToDelete[deleteIndex].Name,
+			To:   summary.ToCreate[createIndex],
+		})
+		summary.ToDelete = append(summary.ToDelete[:deleteIndex:deleteIndex], summary.ToDelete[deleteIndex+1:]...)
+		summary.ToCreate = append(summary.ToCreate[:createIndex:createIndex], summary.ToCreate[createIndex+1:]...)
+	}
+
+	// Map iteration is random, so keep renames in a stable order
+	sort.Slice(summary.ToRename, func(i, j int) bool {
+		return strings.ToLower(summary.ToRename[i].From) < strings.ToLower(summary.ToRename[j].From)
+	})
+	return summary
+}
+
+func indexOfLabel(labels []Label, name string) int {
+	for i, label := range labels {
+		if strings.EqualFold(label.Name, name) {
+			return i
+		}
+	}
+	return -1
+}
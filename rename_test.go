@@ -0,0 +1,122 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPairRenamesExplicit(t *testing.T) {
+	sourceLabels := []Label{
+		{Name: "type: bug", Color: "d73a4a", Description: "Something isn't working"},
+		{Name: "docs", Color: "0075ca"},
+	}
+	destLabels := []Label{
+		{Name: "bug", Color: "ee0701"},
+	}
+
+	items := buildPickerItems(sourceLabels, destLabels)
+	items = pairRenames(items, sourceLabels, map[string]string{"bug": "type: bug"})
+
+	if len(items) != 2 {
+		t.Fatalf("Expected the rename and one new label, got %d items", len(items))
+	}
+	rename := items[0]
+	if !rename.Rename || rename.Suggested || !rename.Selected {
+		t.Errorf("Explicit rename should be selected and not suggested, got %+v", rename)
+	}
+	if rename.Current.Name != "bug" || rename.Label.Name != "type: bug" {
+		t.Errorf("Rename = %s → %s, want bug → type: bug", rename.Current.Name, rename.Label.Name)
+	}
+	if items[1].Label.Name != "docs" {
+		t.Errorf("New labels should follow renames, got %s", items[1].Label.Name)
+	}
+}
+
+func TestPairRenamesSuggested(t *testing.T) {
+	sourceLabels := []Label{
+		{Name: "type: bug", Color: "d73a4a", Description: "Something isn't working"},
+		{Name: "type: chore", Color: "cccccc"},
+		{Name: "help", Color: "008672", Description: "Extra attention is needed"},
+		{Name: "needs help", Color: "008672", Description: "Extra attention is needed"},
+	}
+	destLabels := []Label{
+		{Name: "bug", Color: "#D73A4A", Description: "Something isn't working"},
+		{Name: "chore", Color: "cccccc"},
+		{Name: "help wanted", Color: "008672", Description: "Extra attention is needed"},
+	}
+
+	items := pairRenames(buildPickerItems(sourceLabels, destLabels), sourceLabels, nil)
+
+	var renames []string
+	for _, item := range items {
+		if item.Rename {
+			if !item.Suggested {
+				t.Errorf("Similarity match %s should only be suggested", item.Label.Name)
+			}
+			renames = append(renames, item.Current.Name+" → "+item.Label.Name)
+		}
+	}
+
+	// chore has no description, and help wanted matches two new labels
+	if strings.Join(renames, ", ") != "bug → type: bug" {
+		t.Errorf("Renames = %v, want [bug → type: bug]", renames)
+	}
+}
+
+func TestSummarizeRenames(t *testing.T) {
+	sourceLabels := []Label{{Name: "type: bug", Color: "d73a4a", Description: "Something isn't working"}}
+	destLabels := []Label{{Name: "bug", Color: "d73a4a", Description: "Something isn't working"}}
+
+	items := pairRenames(buildPickerItems(sourceLabels, destLabels), sourceLabels, nil)
+	selectItems(items, sourceLabels, nil, nil, false)
+
+	// An unconfirmed suggestion keeps the old label and adds the new one
+	summary := summarize(items, destLabels)
+	if len(summary.ToRename) != 0 || len(summary.ToCreate) != 1 || len(summary.ToDelete) != 0 || len(summary.ToKeep) != 1 {
+		t.Errorf("Unconfirmed rename: %+v", summary)
+	}
+
+	items[0].Selected = true
+	summary = summarize(items, destLabels)
+	if len(summary.ToRename) != 1 || len(summary.ToCreate) != 0 || len(summary.ToDelete) != 0 {
+		t.Fatalf("Confirmed rename: %+v", summary)
+	}
+	if summary.ToRename[0].From != "bug" || summary.ToRename[0].To.Name != "type: bug" {
+		t.Errorf("Unexpected rename %+v", summary.ToRename[0])
+	}
+}
+
+func TestApplyRenames(t *testing.T) {
+	ms := useMemStore(t)
+	ms.labels["owner/repo"] = []Label{{Name: "bug", Color: "ee0701"}}
+
+	summary := applyRenames(calculateActions(
+		[]Label{{Name: "type: bug", Color: "d73a4a"}},
+		ms.labels["owner/repo"],
+	), map[string]string{"bug": "type: bug"})
+
+	plan := newPlan(summary, "labels.yaml", "owner/repo", ms.labels["owner/repo"])
+	if len(plan.Operations) != 1 || plan.Operations[0].Action != "rename" || plan.Operations[0].From != "bug" {
+		t.Fatalf("Unexpected operations %+v", plan.Operations)
+	}
+	if got := describeOperation("owner/repo", plan.Operations[0]); !strings.Contains(got, `PATCH  repos/owner/repo/labels/bug  new_name="type: bug"`) {
+		t.Errorf("describeOperation() = %q", got)
+	}
+
+	roundTrip, err := planSummary(plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	if err := applyChangesTo(&out, roundTrip, "owner/repo"); err != nil {
+		t.Fatal(err)
+	}
+
+	labels := ms.labels["owner/repo"]
+	if len(labels) != 1 || labels[0].Name != "type: bug" || labels[0].Color != "d73a4a" {
+		t.Errorf("Labels after rename = %+v", labels)
+	}
+	if !strings.Contains(out.String(), "Renaming bug to type: bug") {
+		t.Errorf("Unexpected output:\n%s", out.String())
+	}
+}
@@ -0,0 +1,9 @@
+//go:build windows
+
+package main
+
+import "os"
+
+// Windows has no resize signal, so the picker picks up a new size on the
+// next keypress
+func notifyResize(c chan<- os.Signal) {}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultAPIURL = "https://api.github.com"
+
+// restStore talks to the GitHub REST API over HTTP. It is safe for
+// concurrent use; when GitHub rate limits one request, every request waits.
+type restStore struct {
+	baseURL string
+	token   string
+	client  *http.Client
+	sleep   func(time.Duration)
+	now     func() time.Time
+
+	mu         sync.Mutex
+	pauseUntil time.Time // No requests are sent before this time
+}
+
+func newRESTStore(baseURL, token string) *restStore {
+	return &restStore{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		token:   token,
+		client:  &http.Client{Timeout: 30 * time.Second},
+		sleep:   time.Sleep,
+		now:     time.Now,
+	}
+}
+
+func (s *restStore) FetchLabels(repo string) ([]Label, error) {
+	labels := []Label{}
+	err := s.getAll(fmt.Sprintf("repos/%s/labels?per_page=100", repo), func(dec *json.Decoder) error {
+		var page []Label
+		if err := dec.Decode(&page); err != nil {
+			return err
+		}
+		labels = append(labels, page...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return labels, nil
+}
+
+func (s *restStore) CreateLabel(repo string, label Label) error {
+	body := map[string]string{
+		"name":        label.Name,
+		"color":       strings.TrimPrefix(label.Color, "#"),
+		"description": label.Description,
+	}
+	return s.do("POST", fmt.Sprintf("repos/%s/labels", repo), body, nil)
+}
+
+func (s *restStore) UpdateLabel(repo string, name string, label Label) error {
+	body := map[string]string{
+		"color":       strings.TrimPrefix(label.Color, "#"),
+		"description": label.Description,
+	}
+	if label.Name != name {
+		body["new_name"] = label.Name
+	}
+	return s.do("PATCH", fmt.Sprintf("repos/%s/labels/%s", repo, url.PathEscape(name)), body, nil)
+}
+
+func (s *restStore) DeleteLabel(repo string, name string) error {
+	return s.do("DELETE", fmt.Sprintf("repos/%s/labels/%s", repo, url.PathEscape(name)), nil, nil)
+}
+
+func (s *restStore) ListOrgRepos(org string) ([]Repo, error) {
+	repos := []Repo{}
+	err := s.getAll(fmt.Sprintf("orgs/%s/repos?per_page=100&type=all", url.PathEscape(org)), func(dec *json.Decoder) error {
+		var page []Repo
+		if err := dec.Decode(&page); err != nil {
+			return err
+		}
+		repos = append(repos, page...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return repos, nil
+}
+
+func (s *restStore) LabelUsage(repo string, names []string) (map[string]LabelUses, error) {
+	query, variables, err := usageQuery(repo, names)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := s.graphql(query, variables)
+	if err != nil {
+		return nil, err
+	}
+	return decodeUsage(data, names)
+}
+
+func (s *restStore) ListLabeledIssues(repo string, label string) ([]Issue, error) {
+	return listLabeledIssues(repo, label, s.graphql)
+}
+
+// Sends a GraphQL query and returns the raw response
+func (s *restStore) graphql(query string, variables map[string]string) ([]byte, error) {
+	resp, err := s.request("POST", s.graphqlURL(), map[string]interface{}{
+		"query":     query,
+		"variables": variables,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	return io.ReadAll(resp.Body)
+}
+
+func (s *restStore) AddIssueLabels(repo string, number int, labels []string) error {
+	body := map[string][]string{"labels": labels}
+	return s.do("POST", fmt.Sprintf("repos/%s/issues/%d/labels", repo, number), body, nil)
+}
+
+func (s *restStore) RemoveIssueLabel(repo string, number int, label string) error {
+	return s.do("DELETE", fmt.Sprintf("repos/%s/issues/%d/labels/%s", repo, number, url.PathEscape(label)), nil, nil)
+}
+
+// Returns the GraphQL endpoint, which on Enterprise Server sits beside /api/v3
+func (s *restStore) graphqlURL() string {
+	if strings.HasSuffix(s.baseURL, "/api/v3") {
+		return strings.TrimSuffix(s.baseURL, "/v3") + "/graphql"
+	}
+	return s.baseURL + "/graphql"
+}
+
+// Sends a request and decodes the JSON response into out, if given
+func (s *restStore) do(method, path string, body, out interface{}) error {
+	resp, err := s.request(method, s.baseURL+"/"+path, body)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return fmt.Errorf("failed to parse response: %v", err)
+		}
+	}
+	return nil
+}
+
+// Fetches every page of a list endpoint, following the Link header
+func (s *restStore) getAll(path string, decodePage func(dec *json.Decoder) error) error {
+	next := s.baseURL + "/" + path
+	for page := 1; next != ""; page++ {
+		resp, err := s.request("GET", next, nil)
+		if err != nil {
+			return err
+		}
+
+		err = decodePage(json.NewDecoder(resp.Body))
+		_ = resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("failed to parse page %d: %v", page, err)
+		}
+
+		next = nextPageURL(resp.Header.Get("Link"))
+	}
+	return nil
+}
+
+// Sends a request and turns error responses into APIErrors. Rate-limited
+// requests are retried after the wait GitHub asks for.
+func (s *restStore) request(method, rawURL string, body interface{}) (*http.Response, error) {
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return nil, err
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		s.waitForRateLimit()
+		LogDebug("%s %s", method, rawURL)
+
+		var reqBody io.Reader
+		if body != nil {
+			reqBody = bytes.NewReader(data)
+		}
+
+		req, err := http.NewRequest(method, rawURL, reqBody)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/vnd.github+json")
+		req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+		req.Header.Set("User-Agent", "gabel/"+Version)
+		if s.token != "" {
+			req.Header.Set("Authorization", "Bearer "+s.token)
+		}
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+
+		resp, err := s.client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		// Out of requests: hold the next one until the limit resets
+		if delay, ok := resetDelay(resp.Header, s.now()); ok && delay <= maxRateLimitWait {
+			s.pause(delay)
+		}
+
+		if resp.StatusCode < 300 {
+			return resp, nil
+		}
+
+		errBody, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		rateLimited := resp.Header.Get("X-RateLimit-Remaining") == "0" || resp.Header.Get("Retry-After") != ""
+		apiErr := newAPIError(resp.StatusCode, errBody, rateLimited)
+		if !errors.Is(apiErr, ErrRateLimited) || attempt >= maxRetries {
+			return nil, apiErr
+		}
+
+		delay := rateLimitDelay(resp.Header, attempt, s.now())
+		if delay > maxRateLimitWait {
+			return nil, apiErr
+		}
+		LogDebug("Rate limited, retrying in %s", delay)
+		s.pause(delay)
+	}
+}
+
+// Holds every request for a while
+func (s *restStore) pause(delay time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if until := s.now().Add(delay); until.After(s.pauseUntil) {
+		s.pauseUntil = until
+	}
+}
+
+// Sleeps until requests may be sent again
+func (s *restStore) waitForRateLimit() {
+	s.mu.Lock()
+	delay := s.pauseUntil.Sub(s.now())
+	s.mu.Unlock()
+
+	if delay > 0 {
+		s.sleep(delay)
+	}
+}
+
+// Returns the rel="next" URL from a Link header, or "" on the last page
+func nextPageURL(link string) string {
+	for _, part := range strings.Split(link, ",") {
+		segments := strings.Split(part, ";")
+		if len(segments) < 2 {
+			continue
+		}
+
+		target := strings.TrimSpace(segments[0])
+		if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+			continue
+		}
+
+		for _, param := range segments[1:] {
+			if strings.TrimSpace(param) == `rel="next"` {
+				return strings.Trim(target, "<>")
+			}
+		}
+	}
+	return ""
+}
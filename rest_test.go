@@ -0,0 +1,340 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Starts a fake GitHub API and returns a store pointed at it
+func newTestRESTStore(t *testing.T, handler http.HandlerFunc) *restStore {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	s := newRESTStore(server.URL, "test-token")
+	s.sleep = func(time.Duration) {} // Retries shouldn't slow the tests down
+	return s
+}
+
+func TestRESTStoreFetchLabels(t *testing.T) {
+	s := newTestRESTStore(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/owner/repo/labels" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer test-token" {
+			t.Errorf("Authorization = %q", got)
+		}
+		_, _ = io.WriteString(w, `[{"id": 1, "name": "bug", "color": "d73a4a", "description": "Broken"}]`)
+	})
+
+	labels, err := s.FetchLabels("owner/repo")
+	if err != nil {
+		t.Fatalf("FetchLabels() error = %v", err)
+	}
+	if len(labels) != 1 || labels[0].Name != "bug" || labels[0].Description != "Broken" {
+		t.Errorf("Unexpected labels %+v", labels)
+	}
+}
+
+func TestRESTStoreMutations(t *testing.T) {
+	var requests []string
+	var bodies []map[string]string
+
+	s := newTestRESTStore(t, func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.EscapedPath())
+
+		body := map[string]string{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		bodies = append(bodies, body)
+
+		w.WriteHeader(http.StatusOK)
+	})
+
+	label := Label{Name: "good first issue", Color: "#7057ff", Description: "Good for newcomers"}
+	if err := s.CreateLabel("owner/repo", label); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.UpdateLabel("owner/repo", label.Name, label); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteLabel("owner/repo", label.Name); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"POST /repos/owner/repo/labels",
+		"PATCH /repos/owner/repo/labels/good%20first%20issue",
+		"DELETE /repos/owner/repo/labels/good%20first%20issue",
+	}
+	if strings.Join(requests, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Requests = %v, want %v", requests, want)
+	}
+
+	if bodies[0]["name"] != "good first issue" || bodies[0]["color"] != "7057ff" {
+		t.Errorf("Unexpected create body %v", bodies[0])
+	}
+	if _, hasName := bodies[1]["name"]; hasName || bodies[1]["description"] != "Good for newcomers" {
+		t.Errorf("Unexpected update body %v", bodies[1])
+	}
+	if _, hasNewName := bodies[1]["new_name"]; hasNewName {
+		t.Errorf("Update without a rename should not send new_name, got %v", bodies[1])
+	}
+}
+
+func TestRESTStoreRename(t *testing.T) {
+	var request string
+	var body map[string]string
+
+	s := newTestRESTStore(t, func(w http.ResponseWriter, r *http.Request) {
+		request = r.Method + " " + r.URL.EscapedPath()
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	label := Label{Name: "type: bug", Color: "d73a4a", Description: "Something isn't working"}
+	if err := s.UpdateLabel("owner/repo", "bug", label); err != nil {
+		t.Fatal(err)
+	}
+
+	if request != "PATCH /repos/owner/repo/labels/bug" {
+		t.Errorf("Request = %q, want PATCH of the old name", request)
+	}
+	if body["new_name"] != "type: bug" || body["color"] != "d73a4a" {
+		t.Errorf("Unexpected rename body %v", body)
+	}
+}
+
+func TestRESTStoreErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		headers map[string]string
+		body    string
+		kind    error
+		message string
+	}{
+		{"not found", 404, nil, `{"message": "Not Found"}`, ErrNotFound, "Not Found (HTTP 404)"},
+		{"forbidden", 403, nil, `{"message": "Must have admin rights to Repository."}`, ErrForbidden, "admin rights"},
+		{"rate limited", 403, map[string]string{"X-RateLimit-Remaining": "0"}, `{"message": "API rate limit exceeded"}`, ErrRateLimited, "rate limit"},
+		{"secondary rate limit", 429, nil, `{"message": "You have exceeded a secondary rate limit"}`, ErrRateLimited, "secondary"},
+		{"validation", 422, nil, `{"message": "Validation Failed", "errors": [{"resource": "Label", "code": "already_exists", "field": "name"}]}`, ErrValidation, "Validation Failed: name already_exists (HTTP 422)"},
+		{"server error", 502, nil, `Bad Gateway`, nil, "Bad Gateway (HTTP 502)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestRESTStore(t, func(w http.ResponseWriter, r *http.Request) {
+				for k, v := range tt.headers {
+					w.Header().Set(k, v)
+				}
+				w.WriteHeader(tt.status)
+				_, _ = io.WriteString(w, tt.body)
+			})
+
+			err := s.CreateLabel("owner/repo", Label{Name: "bug", Color: "d73a4a"})
+
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("Expected *APIError, got %v", err)
+			}
+			if apiErr.StatusCode != tt.status || apiErr.Body != tt.body {
+				t.Errorf("APIError = %d %q, want %d %q", apiErr.StatusCode, apiErr.Body, tt.status, tt.body)
+			}
+			if tt.kind != nil && !errors.Is(err, tt.kind) {
+				t.Errorf("errors.Is(%v, %v) = false", err, tt.kind)
+			}
+			if !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("Error %q should contain %q", err.Error(), tt.message)
+			}
+		})
+	}
+}
+
+func TestGHAPIError(t *testing.T) {
+	err := ghAPIError([]byte(`{"message":"Not Found","documentation_url":"https://docs.github.com"}`), []byte("gh: Not Found (HTTP 404)\n"))
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+
+	err = ghAPIError(nil, []byte("gh: API rate limit exceeded for user. (HTTP 403)\n"))
+	if !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Expected ErrRateLimited, got %v", err)
+	}
+
+	err = ghAPIError(nil, []byte("error connecting to api.github.com\n"))
+	var apiErr *APIError
+	if errors.As(err, &apiErr) || err.Error() != "error connecting to api.github.com" {
+		t.Errorf("Non-HTTP failures should keep gh's message, got %v", err)
+	}
+}
+
+func TestFetchLabelsWrapsTypedErrors(t *testing.T) {
+	old := store
+	defer func() { store = old }()
+
+	store = newTestRESTStore(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = io.WriteString(w, `{"message": "Not Found"}`)
+	})
+
+	_, err := FetchLabels("owner/missing")
+	if !errors.Is(err, ErrNotFound) || !strings.Contains(err.Error(), "repository not found: owner/missing") {
+		t.Errorf("Unexpected error %v", err)
+	}
+}
+
+// Builds fixture pages of up to 100 labels each, the last one partial
+func labelPages(pages int) [][]Label {
+	var result [][]Label
+	for p := 0; p < pages; p++ {
+		size := 100
+		if p == pages-1 {
+			size = 37
+		}
+
+		page := []Label{}
+		for i := 0; i < size; i++ {
+			page = append(page, Label{Name: fmt.Sprintf("label-%d-%d", p+1, i), Color: "d73a4a"})
+		}
+		result = append(result, page)
+	}
+	return result
+}
+
+func TestRESTStoreFetchLabelsPagination(t *testing.T) {
+	for _, pages := range []int{1, 2, 10} {
+		t.Run(fmt.Sprintf("%d pages", pages), func(t *testing.T) {
+			fixtures := labelPages(pages)
+			requests := 0
+
+			var serverURL string
+			s := newTestRESTStore(t, func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				if r.URL.Query().Get("per_page") != "100" {
+					t.Errorf("Expected per_page=100, got %q", r.URL.RawQuery)
+				}
+
+				page := 1
+				if p := r.URL.Query().Get("page"); p != "" {
+					page, _ = strconv.Atoi(p)
+				}
+
+				if page < pages {
+					w.Header().Set("Link", fmt.Sprintf(`<%s/repos/owner/repo/labels?per_page=100&page=%d>; rel="next", <%s/repos/owner/repo/labels?per_page=100&page=%d>; rel="last"`,
+						serverURL, page+1, serverURL, pages))
+				}
+				_ = json.NewEncoder(w).Encode(fixtures[page-1])
+			})
+			serverURL = s.baseURL
+
+			labels, err := s.FetchLabels("owner/repo")
+			if err != nil {
+				t.Fatalf("FetchLabels() error = %v", err)
+			}
+
+			want := (pages-1)*100 + 37
+			if len(labels) != want {
+				t.Errorf("Expected %d labels, got %d", want, len(labels))
+			}
+			if requests != pages {
+				t.Errorf("Expected %d requests, got %d", pages, requests)
+			}
+			if last := labels[len(labels)-1].Name; last != fmt.Sprintf("label-%d-36", pages) {
+				t.Errorf("Last label = %q", last)
+			}
+		})
+	}
+}
+
+func TestNextPageURL(t *testing.T) {
+	tests := []struct {
+		link string
+		want string
+	}{
+		{"", ""},
+		{`<https://api.github.com/repositories/1/labels?page=2>; rel="next", <https://api.github.com/repositories/1/labels?page=5>; rel="last"`, "https://api.github.com/repositories/1/labels?page=2"},
+		{`<https://api.github.com/repositories/1/labels?page=1>; rel="prev", <https://api.github.com/repositories/1/labels?page=1>; rel="first"`, ""},
+	}
+
+	for _, tt := range tests {
+		if got := nextPageURL(tt.link); got != tt.want {
+			t.Errorf("nextPageURL(%q) = %q, want %q", tt.link, got, tt.want)
+		}
+	}
+}
+
+func TestRESTStoreIssueLabels(t *testing.T) {
+	var requests []string
+	var added map[string][]string
+
+	var labelVars []string
+	s := newTestRESTStore(t, func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.EscapedPath()+"?"+r.URL.RawQuery)
+		switch {
+		case r.URL.Path == "/graphql":
+			var body struct {
+				Query     string            `json:"query"`
+				Variables map[string]string `json:"variables"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			labelVars = append(labelVars, body.Variables["label"])
+			switch {
+			case strings.Contains(body.Query, "pullRequests("):
+				_, _ = w.Write([]byte(`{"data": {"repository": {"label": {"items": {"nodes": [{"number": 9, "title": "Fix"}], "pageInfo": {"hasNextPage": false}}}}}}`))
+			case body.Variables["cursor"] == "":
+				_, _ = w.Write([]byte(`{"data": {"repository": {"label": {"items": {"nodes": [{"number": 7, "title": "Crash"}], "pageInfo": {"hasNextPage": true, "endCursor": "c1"}}}}}}`))
+			default:
+				_, _ = w.Write([]byte(`{"data": {"repository": {"label": {"items": {"nodes": [{"number": 3, "title": "Hang"}], "pageInfo": {"hasNextPage": false}}}}}}`))
+			}
+		case r.Method == "POST":
+			_ = json.NewDecoder(r.Body).Decode(&added)
+			_, _ = w.Write([]byte(`[]`))
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	})
+
+	issues, err := s.ListLabeledIssues("owner/repo", "type: bug, crash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var numbers []int
+	for _, issue := range issues {
+		numbers = append(numbers, issue.Number)
+	}
+	if fmt.Sprint(numbers) != "[7 3 9]" {
+		t.Errorf("Issue numbers = %v, want [7 3 9]", numbers)
+	}
+	for _, label := range labelVars {
+		if label != "type: bug, crash" {
+			t.Errorf("The label should be passed whole, got %q", label)
+		}
+	}
+	if err := s.AddIssueLabels("owner/repo", 7, []string{"bug"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RemoveIssueLabel("owner/repo", 7, "type: bug"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"POST /graphql?",
+		"POST /graphql?",
+		"POST /graphql?",
+		"POST /repos/owner/repo/issues/7/labels?",
+		"DELETE /repos/owner/repo/issues/7/labels/type:%20bug?",
+	}
+	if strings.Join(requests, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Requests = %v, want %v", requests, want)
+	}
+	if len(added["labels"]) != 1 || added["labels"][0] != "bug" {
+		t.Errorf("Unexpected add body %v", added)
+	}
+}
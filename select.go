@@ -0,0 +1,123 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Builds the picker items for a destination, with renames paired and the
+// selection flags applied
+func prepareItems(source Manifest, destLabels []Label) []PickerItem {
+	items := buildPickerItems(source.Labels, destLabels)
+	items = pairRenames(items, source.Labels, source.Renames)
+	markMerges(items, source.Labels, source.Merges)
+	setItemOrigins(items, source)
+	markProtected(items)
+	markReadOnly(items)
+	selectItems(items, source.Labels, include, exclude, prune)
+	return items
+}
+
+// Calculates the actions for the final picker selection
+func summarize(items []PickerItem, destLabels []Label) ActionSummary {
+	summary := applyRenames(calculateActions(selectedLabels(items), destLabels), itemRenames(items))
+	return applyMerges(summary, itemMerges(items))
+}
+
+// Applies --include, --exclude and --prune to the initial picker selection
+func selectItems(items []PickerItem, sourceLabels []Label, include, exclude []string, prune bool) {
+	sourceMap := make(map[string]bool)
+	for _, label := range sourceLabels {
+		sourceMap[strings.ToLower(label.Name)] = true
+	}
+
+	for i := range items {
+		item := &items[i]
+		inScope := matchesFilters(item.Label.Name, include, exclude)
+
+		if item.Protected || item.ReadOnly {
+			// Locked labels stay exactly as the destination has them
+			item.Selected = item.IsDestOnly
+			item.MergeInto = ""
+			continue
+		}
+
+		if item.IsDestOnly && item.MergeInto != "" {
+			// Merged labels are deleted once their issues have moved
+			item.Selected = !inScope
+			continue
+		}
+
+		if item.IsDestOnly {
+			// Labels outside the filters are never touched
+			item.Selected = !inScope || !prune || sourceMap[strings.ToLower(item.Label.Name)]
+			continue
+		}
+
+		// Source and differing labels are only copied when in scope, and
+		// suggested renames still need confirming in the picker
+		item.Selected = inScope && !item.Suggested
+	}
+}
+
+// Returns the labels that make up the desired state of the destination
+func selectedLabels(items []PickerItem) []Label {
+	var selected []Label
+	for _, item := range items {
+		if item.Selected {
+			selected = append(selected, item.Label)
+		} else if item.Differs {
+			// Leave the destination version untouched
+			selected = append(selected, item.Current)
+		} else if item.Rename {
+			// Keep the old label and add the new one alongside it
+			selected = append(selected, item.Current, item.Label)
+		}
+	}
+	return selected
+}
+
+// Reports whether a label name passes the include and exclude patterns
+func matchesFilters(name string, include, exclude []string) bool {
+	for _, pattern := range exclude {
+		if globMatch(pattern, name) {
+			return false
+		}
+	}
+
+	if len(include) == 0 {
+		return true
+	}
+
+	for _, pattern := range include {
+		if globMatch(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Matches a label name against a glob pattern, case-insensitively.
+// Supports * (any run of characters, including /) and ? (one character).
+func globMatch(pattern, name string) bool {
+	var expr strings.Builder
+	expr.WriteString("(?is)^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			expr.WriteString(".*")
+		case '?':
+			expr.WriteString(".")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	expr.WriteString("$")
+
+	return regexp.MustCompile(expr.String()).MatchString(name)
+}
+
+// Reports whether a summary would change the destination
+func hasChanges(summary ActionSummary) bool {
+	return len(summary.ToCreate) > 0 || len(summary.ToUpdate) > 0 || len(summary.ToRename) > 0 || len(summary.ToMerge) > 0 || len(summary.ToDelete) > 0
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestGlobMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"bug", "bug", true},
+		{"bug", "Bug", true},
+		{"bug", "bugs", false},
+		{"area/*", "area/ui", true},
+		{"*", "area/ui", true},
+		{"type: *", "type: bug", true},
+		{"p?", "p1", true},
+		{"p?", "p10", false},
+		{"good first issue", "good first issue", true},
+		{"(x)", "(x)", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+"_"+tt.name, func(t *testing.T) {
+			if got := globMatch(tt.pattern, tt.name); got != tt.want {
+				t.Errorf("globMatch(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectItems(t *testing.T) {
+	sourceLabels := []Label{
+		{Name: "bug", Color: "#d73a4a"},
+		{Name: "area/ui", Color: "#0075ca"},
+		{Name: "feature", Color: "#a2eeef"},
+	}
+
+	destLabels := []Label{
+		{Name: "bug", Color: "#ff0000"},
+		{Name: "renovate", Color: "#ffffff"},
+		{Name: "stale", Color: "#cccccc"},
+	}
+
+	items := buildPickerItems(sourceLabels, destLabels)
+	selectItems(items, sourceLabels, []string{"bug", "area/*", "stale"}, []string{"renovate"}, true)
+
+	want := map[string]bool{
+		"renovate": true,  // excluded, never touched
+		"stale":    false, // in scope and pruned
+		"bug":      true,  // differs, in scope
+		"area/ui":  true,  // included
+		"feature":  false, // not included
+	}
+	for _, item := range items {
+		if item.Selected != want[item.Label.Name] {
+			t.Errorf("%s selected = %v, want %v", item.Label.Name, item.Selected, want[item.Label.Name])
+		}
+	}
+
+	summary := calculateActions(selectedLabels(items), destLabels)
+	if len(summary.ToCreate) != 1 || len(summary.ToUpdate) != 1 || len(summary.ToDelete) != 1 {
+		t.Errorf("Expected 1 create, 1 update and 1 delete, got %+v", summary)
+	}
+}
+
+func TestSelectedLabelsKeepsDifferingDest(t *testing.T) {
+	items := []PickerItem{
+		{Label: Label{Name: "bug", Color: "#d73a4a"}, Current: Label{Name: "bug", Color: "#ff0000"}, Differs: true},
+	}
+
+	selected := selectedLabels(items)
+	if len(selected) != 1 || selected[0].Color != "#ff0000" {
+		t.Errorf("Unselected differing label should keep the destination version, got %v", selected)
+	}
+
+	if hasChanges(calculateActions(selected, []Label{items[0].Current})) {
+		t.Error("Keeping the destination version should not change anything")
+	}
+}
@@ -21,7 +21,11 @@ echo "4. Testing debug output..."
 echo
 
 echo "5. Testing GitHub CLI check..."
-PATH="" ./gabel foo/bar foo/baz 2>&1
+PATH="" ./gabel --backend gh foo/bar foo/baz 2>&1
+echo
+
+echo "6. Testing missing token..."
+PATH="" GH_TOKEN="" GITHUB_TOKEN="" ./gabel foo/bar foo/baz 2>&1
 echo
 
 echo "=== Tests complete ==="
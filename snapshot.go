@@ -0,0 +1,345 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+)
+
+// Snapshot is a destination's labels as they were before gabel changed them
+type Snapshot struct {
+	Repo    string            `json:"repo"`
+	Taken   time.Time         `json:"taken"`
+	Labels  []Label           `json:"labels"`
+	Issues  map[string][]int  `json:"issues,omitempty"`  // Issues and PRs of each deleted or merged label
+	Gained  map[string][]int  `json:"gained,omitempty"`  // Issues and PRs a merge adds each target label to
+	Renames map[string]string `json:"renames,omitempty"` // New name → old name
+
+	// The snapshot a restore was rolling back to when it took this one
+	RestoredFrom string `json:"restored_from,omitempty"`
+}
+
+// Set while gabel restore applies a snapshot, so the snapshot the restore
+// itself takes is marked and not picked as the repo's latest
+var restoringFrom string
+
+// Returns the directory gabel keeps snapshots and other state in:
+// $GABEL_STATE_DIR, $XDG_STATE_HOME/gabel or ~/.local/state/gabel
+func stateDir() (string, error) {
+	if dir := os.Getenv("GABEL_STATE_DIR"); dir != "" {
+		return dir, nil
+	}
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return filepath.Join(dir, "gabel"), nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find the state directory: %v", err)
+	}
+	return filepath.Join(home, ".local", "state", "gabel"), nil
+}
+
+// Returns the directory holding a repo's snapshots
+func snapshotDir(repo string) (string, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return "", err
+	}
+	name := strings.NewReplacer("/", "_", ":", "_").Replace(repo)
+	return filepath.Join(dir, "snapshots", name), nil
+}
+
+// Saves the destination's current labels before a summary is applied,
+// along with the issues of every label it deletes or merges
+func takeSnapshot(repo string, summary ActionSummary) (string, error) {
+	labels, err := FetchLabels(repo)
+	if err != nil {
+		return "", err
+	}
+
+	snapshot := Snapshot{Repo: repo, Taken: time.Now().UTC(), Labels: labels, RestoredFrom: restoringFrom}
+
+	if snapshotIssues {
+		removed := append([]Label{}, summary.ToDelete...)
+		for _, merge := range summary.ToMerge {
+			removed = append(removed, Label{Name: merge.From})
+		}
+		for _, label := range removed {
+			issues, err := ListLabeledIssues(repo, label.Name)
+			if err != nil {
+				return "", fmt.Errorf("failed to list issues labeled %s: %w", label.Name, err)
+			}
+			if len(issues) == 0 {
+				continue
+			}
+			if snapshot.Issues == nil {
+				snapshot.Issues = make(map[string][]int)
+			}
+			for _, issue := range issues {
+				snapshot.Issues[label.Name] = append(snapshot.Issues[label.Name], issue.Number)
+			}
+		}
+
+		if err := recordGained(&snapshot, repo, summary.ToMerge); err != nil {
+			return "", err
+		}
+	}
+
+	for _, rename := range summary.ToRename {
+		if snapshot.Renames == nil {
+			snapshot.Renames = make(map[string]string)
+		}
+		snapshot.Renames[rename.To.Name] = rename.From
+	}
+
+	dir, err := snapshotDir(repo)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+
+	// Names are timestamps; a snapshot taken in the same millisecond as
+	// another, such as a quick restore's, moves up a millisecond rather
+	// than overwriting it
+	path := filepath.Join(dir, snapshot.Taken.Format("20060102T150405.000Z")+".json")
+	for {
+		if _, err := os.Stat(path); err != nil {
+			break
+		}
+		snapshot.Taken = snapshot.Taken.Add(time.Millisecond)
+		path = filepath.Join(dir, snapshot.Taken.Format("20060102T150405.000Z")+".json")
+	}
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// Records which issues and PRs each merge target will be added to: those
+// of the merged labels that don't have the target already
+func recordGained(snapshot *Snapshot, repo string, merges []LabelMerge) error {
+	for _, merge := range merges {
+		target := merge.Into.Name
+		from := snapshot.Issues[merge.From]
+		if len(from) == 0 {
+			continue
+		}
+
+		issues, err := ListLabeledIssues(repo, target)
+		if err != nil {
+			return fmt.Errorf("failed to list issues labeled %s: %w", target, err)
+		}
+		had := make(map[int]bool)
+		for _, issue := range issues {
+			had[issue.Number] = true
+		}
+		for _, number := range snapshot.Gained[target] {
+			had[number] = true
+		}
+
+		for _, number := range from {
+			if had[number] {
+				continue
+			}
+			if snapshot.Gained == nil {
+				snapshot.Gained = make(map[string][]int)
+			}
+			snapshot.Gained[target] = append(snapshot.Gained[target], number)
+			had[number] = true
+		}
+	}
+	return nil
+}
+
+// Loads a snapshot file
+func loadSnapshot(path string) (Snapshot, error) {
+	var snapshot Snapshot
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return snapshot, fmt.Errorf("failed to read snapshot: %v", err)
+	}
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return snapshot, fmt.Errorf("failed to parse snapshot %s: %v", path, err)
+	}
+	if !isValidRepo(snapshot.Repo) {
+		return snapshot, fmt.Errorf("snapshot %s has an invalid repo: %q", path, snapshot.Repo)
+	}
+	return snapshot, nil
+}
+
+// Finds a snapshot by path, or the latest one of a repo. Snapshots taken
+// by a restore are skipped, so restoring twice doesn't undo the restore.
+func findSnapshot(arg string) (string, error) {
+	if _, err := os.Stat(arg); err == nil || !isValidRepo(arg) {
+		return arg, nil
+	}
+
+	dir, err := snapshotDir(arg)
+	if err != nil {
+		return "", err
+	}
+
+	// Names are timestamps, so the last one is the latest
+	paths, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	sort.Strings(paths)
+	for i := len(paths) - 1; i >= 0; i-- {
+		if !takenByRestore(paths[i]) {
+			return paths[i], nil
+		}
+	}
+	return "", fmt.Errorf("no snapshots of %s in %s", arg, dir)
+}
+
+// Reports whether a snapshot file was taken by gabel restore
+func takenByRestore(path string) bool {
+	var snapshot struct {
+		RestoredFrom string `json:"restored_from"`
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || json.Unmarshal(data, &snapshot) != nil {
+		return false
+	}
+	return snapshot.RestoredFrom != ""
+}
+
+// Calculates the actions that take a repo back to a snapshot. Renamed
+// labels are renamed back so they keep their issues.
+func restoreSummary(snapshot Snapshot, current []Label) ActionSummary {
+	summary := calculateActions(snapshot.Labels, current)
+
+	renames := make(map[string]string)
+	for newName, oldName := range snapshot.Renames {
+		renames[strings.ToLower(newName)] = oldName
+	}
+	return applyRenames(summary, renames)
+}
+
+// Adds deleted and merged labels back to the issues that had them
+func reattachIssues(out io.Writer, repo string, issues map[string][]int) error {
+	names := make([]string, 0, len(issues))
+	for name := range issues {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for i, name := range names {
+		fmt.Fprintf(out, "[%d/%d] Re-attaching %s to %d issues and PRs...\n", i+1, len(names), name, len(issues[name]))
+		for _, number := range issues[name] {
+			if err := AddIssueLabel(repo, number, name); err != nil {
+				return fmt.Errorf("failed to add %s to #%d: %v", name, number, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Takes merge targets back off the issues a merge added them to
+func detachGained(out io.Writer, repo string, gained map[string][]int) error {
+	names := make([]string, 0, len(gained))
+	for name := range gained {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for i, name := range names {
+		fmt.Fprintf(out, "[%d/%d] Removing %s from %d issues and PRs it was merged into...\n", i+1, len(names), name, len(gained[name]))
+		for _, number := range gained[name] {
+			// The label is already gone if the restore deleted it
+			if err := RemoveIssueLabel(repo, number, name); err != nil && !errors.Is(err, ErrNotFound) {
+				return fmt.Errorf("failed to remove %s from #%d: %v", name, number, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Rolls a repo back to a snapshot taken before an apply
+func runRestore(cmd *cobra.Command, args []string) {
+	InitLogger(debug)
+
+	path, err := findSnapshot(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	snapshot, err := loadSnapshot(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	if err := initStore(snapshot.Repo); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(exitError)
+	}
+
+	fmt.Fprintf(os.Stderr, "Fetching labels from %s...\n", snapshot.Repo)
+	current, err := FetchLabels(snapshot.Repo)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error fetching labels from %s: %v\n", snapshot.Repo, err)
+		os.Exit(exitError)
+	}
+
+	summary := restoreSummary(snapshot, current)
+	if !hasChanges(summary) && len(snapshot.Issues) == 0 && len(snapshot.Gained) == 0 {
+		fmt.Printf("%s already matches the snapshot. Nothing to do.\n", snapshot.Repo)
+		os.Exit(exitNoChanges)
+	}
+
+	fmt.Printf("Restoring %s to its snapshot from %s\n", snapshot.Repo, snapshot.Taken.Local().Format("2006-01-02 15:04:05"))
+	printActions(summary)
+	if len(snapshot.Issues) > 0 {
+		fmt.Printf("  • Re-attach %d labels to their issues and PRs\n", len(snapshot.Issues))
+	}
+	if len(snapshot.Gained) > 0 {
+		fmt.Printf("  • Remove %d merge targets from the issues and PRs they were added to\n", len(snapshot.Gained))
+	}
+
+	if !yes {
+		prompt := promptui.Prompt{
+			Label:     "Proceed",
+			IsConfirm: true,
+			Default:   "n",
+		}
+		if _, err := prompt.Run(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: cancelled\n")
+			os.Exit(exitError)
+		}
+	}
+	fmt.Println()
+
+	if hasChanges(summary) {
+		restoringFrom = path
+		if err := applyChanges(summary, snapshot.Repo); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitError)
+		}
+	}
+	if err := reattachIssues(os.Stdout, snapshot.Repo, snapshot.Issues); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	if err := detachGained(os.Stdout, snapshot.Repo, snapshot.Gained); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	os.Exit(exitChanged)
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestStateDir(t *testing.T) {
+	t.Setenv("GABEL_STATE_DIR", "")
+	t.Setenv("XDG_STATE_HOME", "/tmp/state")
+	if dir, _ := stateDir(); dir != filepath.Join("/tmp/state", "gabel") {
+		t.Errorf("stateDir() = %q, want XDG_STATE_HOME/gabel", dir)
+	}
+
+	t.Setenv("GABEL_STATE_DIR", "/tmp/gabel-state")
+	if dir, _ := stateDir(); dir != "/tmp/gabel-state" {
+		t.Errorf("stateDir() = %q, want GABEL_STATE_DIR", dir)
+	}
+
+	t.Setenv("GABEL_STATE_DIR", "")
+	t.Setenv("XDG_STATE_HOME", "")
+	t.Setenv("HOME", "/home/me")
+	if dir, _ := stateDir(); dir != filepath.Join("/home/me", ".local", "state", "gabel") {
+		t.Errorf("stateDir() = %q, want ~/.local/state/gabel", dir)
+	}
+}
+
+func TestApplyAndRestore(t *testing.T) {
+	ms := useMemStore(t)
+	before := []Label{
+		{Name: "bug", Color: "d73a4a"},
+		{Name: "stale", Color: "ffffff"},
+		{Name: "docs", Color: "0075ca"},
+	}
+	ms.labels["owner/repo"] = append([]Label{}, before...)
+	ms.issues["owner/repo"] = []Issue{
+		{Number: 4, Labels: []Label{{Name: "stale"}}},
+		{Number: 9, Labels: []Label{{Name: "stale"}, {Name: "docs"}}},
+	}
+
+	summary := ActionSummary{
+		ToCreate: []Label{{Name: "enhancement", Color: "a2eeef"}},
+		ToRename: []LabelRename{{From: "docs", To: Label{Name: "documentation", Color: "0075ca"}}},
+		ToDelete: []Label{{Name: "stale", Color: "ffffff"}},
+	}
+
+	var out strings.Builder
+	if err := applyChangesTo(&out, summary, "owner/repo"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "Saved snapshot to ") {
+		t.Errorf("Apply should report the snapshot, got:\n%s", out.String())
+	}
+
+	// Deleting a label takes it off its issues
+	for i := range ms.issues["owner/repo"] {
+		issue := &ms.issues["owner/repo"][i]
+		if j := indexOfLabel(issue.Labels, "stale"); j >= 0 {
+			issue.Labels = append(issue.Labels[:j], issue.Labels[j+1:]...)
+		}
+	}
+
+	path, err := findSnapshot("owner/repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot, err := loadSnapshot(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshot.Labels) != 3 || len(snapshot.Issues["stale"]) != 2 || snapshot.Renames["documentation"] != "docs" {
+		t.Fatalf("Unexpected snapshot %+v", snapshot)
+	}
+
+	restore := restoreSummary(snapshot, ms.labels["owner/repo"])
+	if len(restore.ToRename) != 1 || restore.ToRename[0].From != "documentation" || restore.ToRename[0].To.Name != "docs" {
+		t.Errorf("Restore should rename documentation back to docs, got %+v", restore.ToRename)
+	}
+	if len(restore.ToCreate) != 1 || restore.ToCreate[0].Name != "stale" || len(restore.ToDelete) != 1 || restore.ToDelete[0].Name != "enhancement" {
+		t.Errorf("Unexpected restore summary %+v", restore)
+	}
+
+	out.Reset()
+	if err := applyChangesTo(&out, restore, "owner/repo"); err != nil {
+		t.Fatal(err)
+	}
+	if err := reattachIssues(&out, "owner/repo", snapshot.Issues); err != nil {
+		t.Fatal(err)
+	}
+
+	if digest := labelsDigest(ms.labels["owner/repo"]); digest != labelsDigest(before) {
+		t.Errorf("Labels after restore = %+v, want %+v", ms.labels["owner/repo"], before)
+	}
+	for _, issue := range ms.issues["owner/repo"] {
+		if indexOfLabel(issue.Labels, "stale") < 0 {
+			t.Errorf("#%d should have stale back, got %v", issue.Number, issue.Labels)
+		}
+	}
+}
+
+func TestFindSnapshot(t *testing.T) {
+	t.Setenv("GABEL_STATE_DIR", t.TempDir())
+
+	if _, err := findSnapshot("owner/repo"); err == nil {
+		t.Error("Expected an error without snapshots")
+	}
+
+	dir, _ := snapshotDir("ghe.example.com/owner/repo")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"20260101T090000.000Z.json", "20261018T120000.000Z.json", "20260301T090000.000Z.json"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("{}"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	path, err := findSnapshot("ghe.example.com/owner/repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(path) != "20261018T120000.000Z.json" {
+		t.Errorf("findSnapshot() = %q, want the latest", path)
+	}
+	if path, _ := findSnapshot("backup.json"); path != "backup.json" {
+		t.Errorf("A file argument should be used as is, got %q", path)
+	}
+}
+
+func TestRestoreUndoesMerge(t *testing.T) {
+	ms := useMemStore(t)
+	before := []Label{{Name: "bug", Color: "d73a4a"}, {Name: "defect", Color: "ee0701"}}
+	ms.labels["owner/repo"] = append([]Label{}, before...)
+	ms.issues["owner/repo"] = []Issue{
+		{Number: 1, Labels: []Label{{Name: "defect"}}},
+		{Number: 2, Labels: []Label{{Name: "defect"}, {Name: "bug"}}},
+		{Number: 3, Labels: []Label{{Name: "bug"}}},
+	}
+
+	summary := ActionSummary{ToMerge: []LabelMerge{{From: "defect", Into: Label{Name: "bug", Color: "d73a4a"}}}}
+	var out strings.Builder
+	if err := applyChangesTo(&out, summary, "owner/repo"); err != nil {
+		t.Fatal(err)
+	}
+
+	path, err := findSnapshot("owner/repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot, err := loadSnapshot(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(snapshot.Gained) != "map[bug:[1]]" {
+		t.Fatalf("Only #1 gains bug, got %v", snapshot.Gained)
+	}
+
+	// Restore the way runRestore does
+	restoringFrom = path
+	t.Cleanup(func() { restoringFrom = "" })
+	if err := applyChangesTo(&out, restoreSummary(snapshot, ms.labels["owner/repo"]), "owner/repo"); err != nil {
+		t.Fatal(err)
+	}
+	if err := reattachIssues(&out, "owner/repo", snapshot.Issues); err != nil {
+		t.Fatal(err)
+	}
+	if err := detachGained(&out, "owner/repo", snapshot.Gained); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[int]string{1: "[defect]", 2: "[bug defect]", 3: "[bug]"}
+	for _, issue := range ms.issues["owner/repo"] {
+		var names []string
+		for _, label := range issue.Labels {
+			names = append(names, label.Name)
+		}
+		sort.Strings(names)
+		if got := fmt.Sprint(names); got != want[issue.Number] {
+			t.Errorf("#%d labels = %s, want %s", issue.Number, got, want[issue.Number])
+		}
+	}
+
+	// The snapshot the restore took isn't the one to restore next
+	latest, err := findSnapshot("owner/repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest != path {
+		t.Errorf("findSnapshot() = %q, want the apply's snapshot %q", latest, path)
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// SourceConflict is a label that two sources define with different colors
+type SourceConflict struct {
+	Name       string
+	Source     string // Source whose version is used
+	Color      string
+	Other      string // Lower-priority source that disagrees
+	OtherColor string
+}
+
+// Splits a comma-separated source argument, highest priority first
+func splitSources(arg string) []string {
+	var sources []string
+	for _, source := range strings.Split(arg, ",") {
+		if source = strings.TrimSpace(source); source != "" {
+			sources = append(sources, source)
+		}
+	}
+	return sources
+}
+
+// Reports whether every source is a repo or a labels file
+func isValidSource(arg string) bool {
+	sources := splitSources(arg)
+	for _, source := range sources {
+		if !isValidRepo(source) && !isManifestPath(source) {
+			return false
+		}
+	}
+	return len(sources) > 0
+}
+
+// Returns the sources that are repos, so their hosts can be set up
+func sourceRepos(arg string) []string {
+	var repos []string
+	for _, source := range splitSources(arg) {
+		if !isManifestPath(source) {
+			repos = append(repos, source)
+		}
+	}
+	return repos
+}
+
+// Unions several sources. Labels are matched case-insensitively and the
+// first source to define a label wins; a later one that gives it another
+// color is recorded as a conflict. Renames and merges are combined the
+// same way.
+func mergeSources(names []string, manifests []Manifest) Manifest {
+	merged := Manifest{Origins: make(map[string]string)}
+	winners := make(map[string]Label)
+
+	for i, manifest := range manifests {
+		for _, label := range manifest.Labels {
+			key := strings.ToLower(label.Name)
+			winner, exists := winners[key]
+			if !exists {
+				winners[key] = label
+				merged.Labels = append(merged.Labels, label)
+				merged.Origins[key] = names[i]
+				continue
+			}
+			if normalizeColor(winner.Color) != normalizeColor(label.Color) {
+				merged.Conflicts = append(merged.Conflicts, SourceConflict{
+					Name:       winner.Name,
+					Source:     merged.Origins[key],
+					Color:      normalizeColor(winner.Color),
+					Other:      names[i],
+					OtherColor: normalizeColor(label.Color),
+				})
+			}
+		}
+
+		merged.Renames = mergeMappings(merged.Renames, manifest.Renames)
+		merged.Merges = mergeMappings(merged.Merges, manifest.Merges)
+		// A label protected by any source stays protected
+		merged.Protected = append(merged.Protected, manifest.Protected...)
+		merged.Additive = merged.Additive || manifest.Additive
+	}
+	return merged
+}
+
+// Adds the mappings of a lower-priority source to those already collected.
+// Old names are matched case-insensitively, so the higher priority wins.
+func mergeMappings(into, from map[string]string) map[string]string {
+	for old, name := range from {
+		if into == nil {
+			into = make(map[string]string)
+		}
+		if !containsKeyFold(into, old) {
+			into[old] = name
+		}
+	}
+	return into
+}
+
+// Reports whether a mapping has a key, ignoring case
+func containsKeyFold(m map[string]string, key string) bool {
+	for existing := range m {
+		if strings.ToLower(existing) == strings.ToLower(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// Records which source each picker item's label came from, and any
+// conflicting colors in other sources
+func setItemOrigins(items []PickerItem, source Manifest) {
+	if len(source.Origins) == 0 {
+		return
+	}
+
+	for i := range items {
+		item := &items[i]
+		// Destination labels that already match a source label still came
+		// from that source
+		key := strings.ToLower(item.Label.Name)
+		origin, fromSource := source.Origins[key]
+		if !fromSource {
+			continue
+		}
+		item.Origin = origin
+
+		var conflicts []string
+		for _, conflict := range source.Conflicts {
+			if strings.ToLower(conflict.Name) == key {
+				conflicts = append(conflicts, fmt.Sprintf("%s has #%s", conflict.Other, conflict.OtherColor))
+			}
+		}
+		item.Conflict = strings.Join(conflicts, ", ")
+	}
+}
+
+// Warns about labels the sources disagree on
+func printSourceConflicts(w io.Writer, conflicts []SourceConflict) {
+	for _, conflict := range conflicts {
+		fmt.Fprintf(w, "Warning: %s is #%s in %s but #%s in %s; using %s\n",
+			conflict.Name, conflict.Color, conflict.Source, conflict.OtherColor, conflict.Other, conflict.Source)
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitSources(t *testing.T) {
+	got := splitSources("myorg/base, team/labels.yaml,,myorg/go ")
+	want := []string{"myorg/base", "team/labels.yaml", "myorg/go"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("splitSources() = %v, want %v", got, want)
+	}
+
+	if !isValidSource("myorg/base,labels.yaml") {
+		t.Error("A repo and a labels file should be a valid source")
+	}
+	if isValidSource("myorg/base,not a repo") || isValidSource(",") {
+		t.Error("Every source should have to be a repo or a labels file")
+	}
+	if got := sourceRepos("myorg/base,labels.yaml,myorg/go"); !reflect.DeepEqual(got, []string{"myorg/base", "myorg/go"}) {
+		t.Errorf("sourceRepos() = %v", got)
+	}
+}
+
+func TestMergeSources(t *testing.T) {
+	base := Manifest{
+		Labels:  []Label{{Name: "bug", Color: "d73a4a"}, {Name: "docs", Color: "0075ca"}},
+		Renames: map[string]string{"defect": "bug"},
+	}
+	team := Manifest{
+		Labels:  []Label{{Name: "Bug", Color: "#EE0701"}, {Name: "DOCS", Color: "0075CA"}, {Name: "team: web", Color: "c5def5"}},
+		Renames: map[string]string{"Defect": "docs", "feature": "team: web"},
+	}
+
+	merged := mergeSources([]string{"myorg/base", "team.yaml"}, []Manifest{base, team})
+
+	var names []string
+	for _, label := range merged.Labels {
+		names = append(names, label.Name+" "+label.Color)
+	}
+	if got := strings.Join(names, ", "); got != "bug d73a4a, docs 0075ca, team: web c5def5" {
+		t.Errorf("Labels = %s, want the first source's version of each", got)
+	}
+
+	if merged.Origins["bug"] != "myorg/base" || merged.Origins["team: web"] != "team.yaml" {
+		t.Errorf("Origins = %v", merged.Origins)
+	}
+
+	// A different color is a conflict, a different case of the same color isn't
+	want := []SourceConflict{{Name: "bug", Source: "myorg/base", Color: "d73a4a", Other: "team.yaml", OtherColor: "ee0701"}}
+	if !reflect.DeepEqual(merged.Conflicts, want) {
+		t.Errorf("Conflicts = %+v, want %+v", merged.Conflicts, want)
+	}
+
+	// Old names match case-insensitively, so team.yaml's Defect loses too
+	if len(merged.Renames) != 2 || merged.Renames["defect"] != "bug" || merged.Renames["feature"] != "team: web" {
+		t.Errorf("Renames = %v, want the first source's rename of defect", merged.Renames)
+	}
+}
+
+func TestLoadSeveralSources(t *testing.T) {
+	base := writeManifest(t, "base.yaml", "- name: bug\n  color: d73a4a\n")
+	team := writeManifest(t, "team.json", `[{"name": "bug", "color": "ee0701"}, {"name": "team: web", "color": "c5def5"}]`)
+
+	manifest, err := loadSource(base + "," + team)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(manifest.Labels) != 2 || manifest.Labels[0].Color != "d73a4a" || len(manifest.Conflicts) != 1 {
+		t.Errorf("Unexpected manifest %+v", manifest)
+	}
+
+	if _, err := loadSource(base + "," + filepath.Join(t.TempDir(), "missing.yaml")); err == nil || !strings.Contains(err.Error(), "missing.yaml") {
+		t.Errorf("Expected an error naming the missing source, got %v", err)
+	}
+}
+
+func TestSetItemOrigins(t *testing.T) {
+	source := Manifest{
+		Labels:  []Label{{Name: "bug", Color: "d73a4a"}, {Name: "team: web", Color: "c5def5"}, {Name: "docs", Color: "0075ca"}},
+		Origins: map[string]string{"bug": "myorg/base", "team: web": "team.yaml", "docs": "myorg/base"},
+		Conflicts: []SourceConflict{
+			{Name: "bug", Source: "myorg/base", Color: "d73a4a", Other: "team.yaml", OtherColor: "ee0701"},
+			{Name: "docs", Source: "myorg/base", Color: "0075ca", Other: "team.yaml", OtherColor: "1d76db"},
+		},
+	}
+	// The destination already has docs as the source defines it
+	items := prepareItems(source, []Label{{Name: "wontfix", Color: "ffffff"}, {Name: "Docs", Color: "0075ca"}})
+
+	for _, item := range items {
+		switch item.Label.Name {
+		case "bug":
+			if item.Origin != "myorg/base" || item.Conflict != "team.yaml has #ee0701" {
+				t.Errorf("bug: origin %q, conflict %q", item.Origin, item.Conflict)
+			}
+		case "team: web":
+			if item.Origin != "team.yaml" || item.Conflict != "" {
+				t.Errorf("team: web: origin %q, conflict %q", item.Origin, item.Conflict)
+			}
+		case "Docs":
+			if !item.IsDestOnly || item.Origin != "myorg/base" || item.Conflict != "team.yaml has #1d76db" {
+				t.Errorf("Docs: dest-only %v, origin %q, conflict %q", item.IsDestOnly, item.Origin, item.Conflict)
+			}
+		case "wontfix":
+			if item.Origin != "" {
+				t.Errorf("Destination labels have no source, got %q", item.Origin)
+			}
+		}
+	}
+
+	var view string
+	for _, item := range items {
+		if item.Label.Name == "bug" {
+			view = formatPickerItem(item, false)
+		}
+	}
+	if !strings.Contains(view, "[from myorg/base]") || !strings.Contains(view, "conflict: team.yaml has #ee0701") {
+		t.Errorf("Picker should show the origin and conflict, got %q", view)
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// LabelStore reads and writes the labels of GitHub repositories
+type LabelStore interface {
+	FetchLabels(repo string) ([]Label, error)
+	CreateLabel(repo string, label Label) error
+	UpdateLabel(repo string, name string, label Label) error // Renames name to label.Name if they differ
+	DeleteLabel(repo string, name string) error
+	ListOrgRepos(org string) ([]Repo, error)
+	LabelUsage(repo string, names []string) (map[string]LabelUses, error) // Issues and PRs per label, open and closed
+	ListLabeledIssues(repo string, label string) ([]Issue, error)         // Open and closed issues and PRs
+	AddIssueLabels(repo string, number int, labels []string) error
+	RemoveIssueLabel(repo string, number int, label string) error
+}
+
+// The store used for all GitHub operations, set up by initStore
+var store LabelStore = ghStore{}
+
+// Sets up the --backend store for the host of each repo and checks
+// authentication with every host before anything else happens
+func initStore(repos ...string) error {
+	router := hostRouter{}
+	for _, repo := range repos {
+		host, _ := splitRepo(repo)
+		if _, exists := router[host]; exists {
+			continue
+		}
+
+		hostStore, err := newHostStore(host)
+		if err != nil {
+			return err
+		}
+		router[host] = hostStore
+	}
+
+	store = router
+	return nil
+}
+
+// Creates the --backend store for one host
+func newHostStore(host string) (LabelStore, error) {
+	switch backend {
+	case "api":
+		token, err := githubToken(host)
+		if err != nil {
+			return nil, err
+		}
+		LogDebug("Using api backend for %s", host)
+		return newRESTStore(apiURL(host), token), nil
+	case "gh":
+		if err := CheckGitHubCLI(host); err != nil {
+			return nil, err
+		}
+		LogDebug("Using gh backend for %s", host)
+		return ghStore{host: host}, nil
+	}
+	return nil, fmt.Errorf("invalid backend %q. Use 'api' or 'gh'", backend)
+}
+
+// Returns the REST API root for a host
+func apiURL(host string) string {
+	if isGitHubDotCom(host) {
+		return defaultAPIURL
+	}
+	return fmt.Sprintf("https://%s/api/v3", host)
+}
+
+func isGitHubDotCom(host string) bool {
+	return host == "github.com"
+}
+
+// Finds a token for a host in the environment, falling back to gh auth token.
+// Like gh, enterprise hosts use GH_ENTERPRISE_TOKEN or GITHUB_ENTERPRISE_TOKEN.
+func githubToken(host string) (string, error) {
+	envVars := []string{"GH_TOKEN", "GITHUB_TOKEN"}
+	if !isGitHubDotCom(host) {
+		envVars = []string{"GH_ENTERPRISE_TOKEN", "GITHUB_ENTERPRISE_TOKEN"}
+	}
+
+	for _, name := range envVars {
+		if token := strings.TrimSpace(os.Getenv(name)); token != "" {
+			LogDebug("Using token for %s from %s", host, name)
+			return token, nil
+		}
+	}
+
+	if _, err := exec.LookPath("gh"); err == nil {
+		output, err := exec.Command("gh", "auth", "token", "--hostname", host).Output()
+		if token := strings.TrimSpace(string(output)); err == nil && token != "" {
+			LogDebug("Using token for %s from gh auth token", host)
+			return token, nil
+		}
+	}
+
+	if isGitHubDotCom(host) {
+		return "", fmt.Errorf("not authenticated with GitHub.\nSet GH_TOKEN or run: gh auth login")
+	}
+	return "", fmt.Errorf("not authenticated with %s.\nSet GH_ENTERPRISE_TOKEN or run: gh auth login --hostname %s", host, host)
+}
+
+// hostRouter sends each call to the store for the repo's host
+type hostRouter map[string]LabelStore
+
+func (r hostRouter) route(repo string) (LabelStore, string, error) {
+	host, path := splitRepo(repo)
+	hostStore, exists := r[host]
+	if !exists {
+		return nil, "", fmt.Errorf("no GitHub connection set up for %s", host)
+	}
+	return hostStore, path, nil
+}
+
+func (r hostRouter) FetchLabels(repo string) ([]Label, error) {
+	hostStore, path, err := r.route(repo)
+	if err != nil {
+		return nil, err
+	}
+	return hostStore.FetchLabels(path)
+}
+
+func (r hostRouter) CreateLabel(repo string, label Label) error {
+	hostStore, path, err := r.route(repo)
+	if err != nil {
+		return err
+	}
+	return hostStore.CreateLabel(path, label)
+}
+
+func (r hostRouter) UpdateLabel(repo string, name string, label Label) error {
+	hostStore, path, err := r.route(repo)
+	if err != nil {
+		return err
+	}
+	return hostStore.UpdateLabel(path, name, label)
+}
+
+func (r hostRouter) DeleteLabel(repo string, name string) error {
+	hostStore, path, err := r.route(repo)
+	if err != nil {
+		return err
+	}
+	return hostStore.DeleteLabel(path, name)
+}
+
+func (r hostRouter) ListOrgRepos(org string) ([]Repo, error) {
+	hostStore, path, err := r.route(org)
+	if err != nil {
+		return nil, err
+	}
+	return hostStore.ListOrgRepos(path)
+}
+
+func (r hostRouter) LabelUsage(repo string, names []string) (map[string]LabelUses, error) {
+	hostStore, path, err := r.route(repo)
+	if err != nil {
+		return nil, err
+	}
+	return hostStore.LabelUsage(path, names)
+}
+
+func (r hostRouter) ListLabeledIssues(repo string, label string) ([]Issue, error) {
+	hostStore, path, err := r.route(repo)
+	if err != nil {
+		return nil, err
+	}
+	return hostStore.ListLabeledIssues(path, label)
+}
+
+func (r hostRouter) AddIssueLabels(repo string, number int, labels []string) error {
+	hostStore, path, err := r.route(repo)
+	if err != nil {
+		return err
+	}
+	return hostStore.AddIssueLabels(path, number, labels)
+}
+
+func (r hostRouter) RemoveIssueLabel(repo string, number int, label string) error {
+	hostStore, path, err := r.route(repo)
+	if err != nil {
+		return err
+	}
+	return hostStore.RemoveIssueLabel(path, number, label)
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestSplitRepo(t *testing.T) {
+	oldHostname := hostname
+	defer func() { hostname = oldHostname }()
+	hostname = "github.com"
+
+	tests := []struct {
+		repo string
+		host string
+		path string
+	}{
+		{"owner/repo", "github.com", "owner/repo"},
+		{"GHE.example.com/owner/repo", "ghe.example.com", "owner/repo"},
+		{"localhost:8080/owner/repo", "localhost:8080", "owner/repo"},
+	}
+
+	for _, tt := range tests {
+		host, path := splitRepo(tt.repo)
+		if host != tt.host || path != tt.path {
+			t.Errorf("splitRepo(%q) = %q, %q, want %q, %q", tt.repo, host, path, tt.host, tt.path)
+		}
+	}
+
+	// --hostname applies to repos without a host
+	hostname = "ghe.example.com"
+	if host, _ := splitRepo("owner/repo"); host != "ghe.example.com" {
+		t.Errorf("Expected --hostname to apply, got %q", host)
+	}
+}
+
+func TestAPIURL(t *testing.T) {
+	if got := apiURL("github.com"); got != "https://api.github.com" {
+		t.Errorf("apiURL(github.com) = %q", got)
+	}
+	if got := apiURL("ghe.example.com"); got != "https://ghe.example.com/api/v3" {
+		t.Errorf("apiURL(ghe.example.com) = %q", got)
+	}
+}
+
+func TestGitHubTokenPerHost(t *testing.T) {
+	t.Setenv("PATH", "") // No gh fallback
+	t.Setenv("GH_TOKEN", "dotcom-token")
+	t.Setenv("GITHUB_TOKEN", "")
+	t.Setenv("GH_ENTERPRISE_TOKEN", "")
+	t.Setenv("GITHUB_ENTERPRISE_TOKEN", "ghes-token")
+
+	if token, err := githubToken("github.com"); err != nil || token != "dotcom-token" {
+		t.Errorf("githubToken(github.com) = %q, %v", token, err)
+	}
+	if token, err := githubToken("ghe.example.com"); err != nil || token != "ghes-token" {
+		t.Errorf("githubToken(ghe.example.com) = %q, %v", token, err)
+	}
+
+	_ = os.Unsetenv("GITHUB_ENTERPRISE_TOKEN")
+	if _, err := githubToken("ghe.example.com"); err == nil {
+		t.Error("Expected an error without an enterprise token")
+	}
+}
+
+func TestHostRouter(t *testing.T) {
+	oldHostname := hostname
+	defer func() { hostname = oldHostname }()
+	hostname = "github.com"
+
+	serve := func(name string) *restStore {
+		return newTestRESTStore(t, func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/repos/owner/repo/labels" {
+				t.Errorf("Unexpected path %s", r.URL.Path)
+			}
+			_, _ = io.WriteString(w, `[{"name": "`+name+`", "color": "ffffff"}]`)
+		})
+	}
+
+	router := hostRouter{
+		"github.com":      serve("public"),
+		"ghe.example.com": serve("internal"),
+	}
+
+	labels, err := router.FetchLabels("owner/repo")
+	if err != nil || labels[0].Name != "public" {
+		t.Errorf("owner/repo should go to github.com, got %v, %v", labels, err)
+	}
+
+	labels, err = router.FetchLabels("ghe.example.com/owner/repo")
+	if err != nil || labels[0].Name != "internal" {
+		t.Errorf("ghe.example.com/owner/repo should go to the enterprise host, got %v, %v", labels, err)
+	}
+
+	if _, err := router.FetchLabels("other.example.com/owner/repo"); err == nil {
+		t.Error("Expected an error for a host that was not set up")
+	}
+}
+
+// memStore is an in-memory LabelStore for tests
+type memStore struct {
+	mu     sync.Mutex
+	labels map[string][]Label
+	repos  map[string][]Repo
+	usage  map[string]map[string]LabelUses
+	issues map[string][]Issue
+	fail   map[string]error // Errors to return for a repo
+	failOn map[string]error // Errors to return when creating a label
+}
+
+func newMemStore() *memStore {
+	return &memStore{labels: map[string][]Label{}, repos: map[string][]Repo{}, usage: map[string]map[string]LabelUses{}, issues: map[string][]Issue{}, fail: map[string]error{}, failOn: map[string]error{}}
+}
+
+func (m *memStore) FetchLabels(repo string) ([]Label, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail[repo]; err != nil {
+		return nil, err
+	}
+	labels, exists := m.labels[repo]
+	if !exists {
+		return nil, &APIError{Kind: ErrNotFound, StatusCode: 404, Message: "Not Found"}
+	}
+	return append([]Label{}, labels...), nil
+}
+
+func (m *memStore) CreateLabel(repo string, label Label) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.failOn[label.Name]; err != nil {
+		return err
+	}
+	m.labels[repo] = append(m.labels[repo], label)
+	return nil
+}
+
+func (m *memStore) UpdateLabel(repo string, name string, label Label) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, existing := range m.labels[repo] {
+		if strings.EqualFold(existing.Name, name) {
+			m.labels[repo][i] = label
+			return nil
+		}
+	}
+	return &APIError{Kind: ErrNotFound, StatusCode: 404, Message: "Not Found"}
+}
+
+func (m *memStore) DeleteLabel(repo string, name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, existing := range m.labels[repo] {
+		if strings.EqualFold(existing.Name, name) {
+			m.labels[repo] = append(m.labels[repo][:i], m.labels[repo][i+1:]...)
+			return nil
+		}
+	}
+	return &APIError{Kind: ErrNotFound, StatusCode: 404, Message: "Not Found"}
+}
+
+func (m *memStore) ListOrgRepos(org string) ([]Repo, error) {
+	return m.repos[org], nil
+}
+
+func (m *memStore) LabelUsage(repo string, names []string) (map[string]LabelUses, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail[repo]; err != nil {
+		return nil, err
+	}
+	usage := make(map[string]LabelUses)
+	for _, name := range names {
+		usage[name] = m.usage[repo][name]
+	}
+	return usage, nil
+}
+
+func (m *memStore) ListLabeledIssues(repo string, label string) ([]Issue, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var issues []Issue
+	for _, issue := range m.issues[repo] {
+		if indexOfLabel(issue.Labels, label) >= 0 {
+			issues = append(issues, issue)
+		}
+	}
+	return issues, nil
+}
+
+func (m *memStore) AddIssueLabels(repo string, number int, labels []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, issue := range m.issues[repo] {
+		if issue.Number == number {
+			for _, name := range labels {
+				if indexOfLabel(issue.Labels, name) < 0 {
+					m.issues[repo][i].Labels = append(m.issues[repo][i].Labels, Label{Name: name})
+				}
+			}
+			return nil
+		}
+	}
+	return &APIError{Kind: ErrNotFound, StatusCode: 404, Message: "Not Found"}
+}
+
+func (m *memStore) RemoveIssueLabel(repo string, number int, label string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, issue := range m.issues[repo] {
+		if j := indexOfLabel(issue.Labels, label); issue.Number == number && j >= 0 {
+			m.issues[repo][i].Labels = append(issue.Labels[:j:j], issue.Labels[j+1:]...)
+			return nil
+		}
+	}
+	return &APIError{Kind: ErrNotFound, StatusCode: 404, Message: "Not Found"}
+}
+
+// Swaps in a memStore for the duration of a test
+func useMemStore(t *testing.T) *memStore {
+	t.Helper()
+	old := store
+	t.Cleanup(func() { store = old })
+
+	// Applies save snapshots, so keep them out of the real state directory
+	t.Setenv("GABEL_STATE_DIR", t.TempDir())
+
+	m := newMemStore()
+	store = m
+	return m
+}
@@ -2,21 +2,72 @@ package main
 
 // Label represents a GitHub label
 type Label struct {
-	Name        string `json:"name"`
-	Color       string `json:"color"`
-	Description string `json:"description"`
+	Name        string `json:"name" yaml:"name"`
+	Color       string `json:"color" yaml:"color"`
+	Description string `json:"description" yaml:"description,omitempty"`
 }
 
 // PickerItem represents a label in the picker with selection state
 type PickerItem struct {
 	Label      Label
+	Current    Label // destination version when Differs or Rename is set
 	Selected   bool
 	IsDestOnly bool
+	Differs    bool
+	Rename     bool      // Current is renamed to Label when selected
+	Suggested  bool      // Rename guessed from a matching color and description
+	Uses       LabelUses // Issues and PRs with this label (dest only)
+	MergeInto  string    // Label that takes over this one's issues when it is deleted
+	Modified   bool      // Label was edited in the picker
+	Origin     string    // Source the label came from, with several sources
+	Conflict   string    // Other sources that give the label another color
+	Protected  bool      // Destination label matches a protected pattern
+	ReadOnly   bool      // Destination-only label kept by --no-delete
 }
 
 // ActionSummary describes what will happen to labels
 type ActionSummary struct {
 	ToCreate []Label
+	ToUpdate []Label
+	ToRename []LabelRename
+	ToMerge  []LabelMerge
 	ToDelete []Label
 	ToKeep   []Label
-}
\ No newline at end of file
+}
+
+// LabelMerge moves every issue and PR from one label to another, then
+// deletes the old label
+type LabelMerge struct {
+	From string
+	Into Label
+}
+
+// LabelRename renames an existing label, keeping it on issues and PRs
+type LabelRename struct {
+	From string
+	To   Label
+}
+
+// Repo is a repository found when listing an organization
+type Repo struct {
+	FullName string   `json:"full_name"`
+	Name     string   `json:"name"`
+	Topics   []string `json:"topics"`
+	Archived bool     `json:"archived"`
+}
+
+// LabelUses counts the open and closed issues and PRs that have a label
+type LabelUses struct {
+	OpenIssues   int
+	ClosedIssues int
+	OpenPRs      int
+	ClosedPRs    int  // Closed or merged
+	Unknown      bool // Usage couldn't be checked
+}
+
+// Issue is a GitHub issue or pull request
+type Issue struct {
+	Number int     `json:"number"`
+	Title  string  `json:"title"`
+	Labels []Label `json:"labels"`
+}
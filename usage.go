@@ -0,0 +1,350 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// How many labels are counted per GraphQL query
+const usageBatchSize = 50
+
+// A label that would be deleted while it is still on issues or PRs
+type inUseLabel struct {
+	Label Label
+	Uses  LabelUses
+}
+
+// Total is the number of issues and PRs with the label
+func (u LabelUses) Total() int {
+	return u.OpenIssues + u.ClosedIssues + u.OpenPRs + u.ClosedPRs
+}
+
+// Reports whether a label may be on issues or PRs: it has some, or its
+// usage couldn't be checked
+func (u LabelUses) inUse() bool {
+	return u.Unknown || u.Total() > 0
+}
+
+// Builds a GraphQL query counting the open and closed issues and PRs of
+// each label, with one aliased field per label so a batch takes a single
+// request
+func usageQuery(repo string, names []string) (string, map[string]string, error) {
+	owner, name, ok := strings.Cut(repo, "/")
+	if !ok {
+		return "", nil, fmt.Errorf("invalid repo %q", repo)
+	}
+
+	variables := map[string]string{"owner": owner, "name": name}
+	params := []string{"$owner: String!", "$name: String!"}
+	var fields strings.Builder
+	for i, label := range names {
+		variables[fmt.Sprintf("l%d", i)] = label
+		params = append(params, fmt.Sprintf("$l%d: String!", i))
+		fmt.Fprintf(&fields, " l%d: label(name: $l%d) {"+
+			" openIssues: issues(states: [OPEN]) { totalCount }"+
+			" closedIssues: issues(states: [CLOSED]) { totalCount }"+
+			" openPRs: pullRequests(states: [OPEN]) { totalCount }"+
+			" closedPRs: pullRequests(states: [CLOSED, MERGED]) { totalCount } }", i, i)
+	}
+
+	query := fmt.Sprintf("query(%s) { repository(owner: $owner, name: $name) {%s } }", strings.Join(params, ", "), fields.String())
+	return query, variables, nil
+}
+
+// Decodes a usageQuery response into counts keyed by label name.
+// Labels that don't exist count as unused.
+func decodeUsage(data []byte, names []string) (map[string]LabelUses, error) {
+	type count struct {
+		TotalCount int `json:"totalCount"`
+	}
+	var resp struct {
+		Data struct {
+			Repository map[string]*struct {
+				OpenIssues   count `json:"openIssues"`
+				ClosedIssues count `json:"closedIssues"`
+				OpenPRs      count `json:"openPRs"`
+				ClosedPRs    count `json:"closedPRs"`
+			} `json:"repository"`
+		} `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse label usage: %v", err)
+	}
+	if len(resp.Errors) > 0 {
+		return nil, fmt.Errorf("failed to count label usage: %s", resp.Errors[0].Message)
+	}
+
+	usage := make(map[string]LabelUses)
+	for i, name := range names {
+		if label := resp.Data.Repository[fmt.Sprintf("l%d", i)]; label != nil {
+			usage[name] = LabelUses{
+				OpenIssues:   label.OpenIssues.TotalCount,
+				ClosedIssues: label.ClosedIssues.TotalCount,
+				OpenPRs:      label.OpenPRs.TotalCount,
+				ClosedPRs:    label.ClosedPRs.TotalCount,
+			}
+		} else {
+			usage[name] = LabelUses{}
+		}
+	}
+	return usage, nil
+}
+
+// How many issues or PRs are listed per GraphQL page
+const labeledPageSize = 100
+
+// Builds a GraphQL query for a page of a label's issues or pullRequests.
+// label(name:) matches the name exactly, unlike the REST issues?labels=
+// filter, which splits names on commas.
+func labeledIssuesQuery(repo, label, kind, cursor string) (string, map[string]string, error) {
+	owner, name, ok := strings.Cut(repo, "/")
+	if !ok {
+		return "", nil, fmt.Errorf("invalid repo %q", repo)
+	}
+
+	variables := map[string]string{"owner": owner, "name": name, "label": label}
+	params := "$owner: String!, $name: String!, $label: String!"
+	after := ""
+	if cursor != "" {
+		variables["cursor"] = cursor
+		params += ", $cursor: String!"
+		after = ", after: $cursor"
+	}
+
+	query := fmt.Sprintf("query(%s) { repository(owner: $owner, name: $name) { label(name: $label) {"+
+		" items: %s(first: %d%s) { nodes { number title } pageInfo { hasNextPage endCursor } } } } }",
+		params, kind, labeledPageSize, after)
+	return query, variables, nil
+}
+
+// Decodes a labeledIssuesQuery response into its issues and the cursor
+// of the next page, which is "" after the last one. A label that doesn't
+// exist has no issues.
+func decodeLabeledIssues(data []byte) ([]Issue, string, error) {
+	var resp struct {
+		Data struct {
+			Repository struct {
+				Label *struct {
+					Items struct {
+						Nodes    []Issue `json:"nodes"`
+						PageInfo struct {
+							HasNextPage bool   `json:"hasNextPage"`
+							EndCursor   string `json:"endCursor"`
+						} `json:"pageInfo"`
+					} `json:"items"`
+				} `json:"label"`
+			} `json:"repository"`
+		} `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, "", fmt.Errorf("failed to parse issues: %v", err)
+	}
+	if len(resp.Errors) > 0 {
+		return nil, "", fmt.Errorf("failed to list issues: %s", resp.Errors[0].Message)
+	}
+
+	label := resp.Data.Repository.Label
+	if label == nil {
+		return nil, "", nil
+	}
+	next := ""
+	if label.Items.PageInfo.HasNextPage {
+		next = label.Items.PageInfo.EndCursor
+	}
+	return label.Items.Nodes, next, nil
+}
+
+// Lists every issue and then every PR with a label, a page at a time,
+// sending each GraphQL query with graphql
+func listLabeledIssues(repo, label string, graphql func(query string, variables map[string]string) ([]byte, error)) ([]Issue, error) {
+	issues := []Issue{}
+	for _, kind := range []string{"issues", "pullRequests"} {
+		for cursor := ""; ; {
+			query, variables, err := labeledIssuesQuery(repo, label, kind, cursor)
+			if err != nil {
+				return nil, err
+			}
+			data, err := graphql(query, variables)
+			if err != nil {
+				return nil, err
+			}
+			page, next, err := decodeLabeledIssues(data)
+			if err != nil {
+				return nil, err
+			}
+			issues = append(issues, page...)
+			if next == "" {
+				break
+			}
+			cursor = next
+		}
+	}
+	return issues, nil
+}
+
+// Fetches usage counts for labels, keyed by lowercase name
+func fetchUsage(repo string, labels []Label) (map[string]LabelUses, error) {
+	names := make([]string, 0, len(labels))
+	for _, label := range labels {
+		names = append(names, label.Name)
+	}
+
+	counts, err := LabelUsage(repo, names)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := make(map[string]LabelUses)
+	for name, count := range counts {
+		usage[strings.ToLower(name)] = count
+	}
+	return usage, nil
+}
+
+// Records usage on the destination-only items, the ones that can be
+// deleted. Without usage every count is unknown.
+func setItemUsage(items []PickerItem, usage map[string]LabelUses) {
+	for i := range items {
+		if items[i].IsDestOnly {
+			items[i].Uses = usesOf(usage, items[i].Label.Name)
+		}
+	}
+}
+
+// Looks up a label's usage, which is unknown if it wasn't counted
+func usesOf(usage map[string]LabelUses, name string) LabelUses {
+	uses, known := usage[strings.ToLower(name)]
+	if !known {
+		return LabelUses{Unknown: true}
+	}
+	return uses
+}
+
+// Counts usage of every label the picker could delete
+func loadItemUsage(repo string, items []PickerItem) {
+	var destOnly []Label
+	for _, item := range items {
+		if item.IsDestOnly {
+			destOnly = append(destOnly, item.Label)
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Counting label usage in %s...\n", repo)
+	usage, err := fetchUsage(repo, destOnly)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: couldn't count label usage in %s: %v\n", repo, err)
+	}
+	setItemUsage(items, usage)
+}
+
+// Returns the labels a summary deletes that are still in use
+func inUseDeletes(summary ActionSummary, usage map[string]LabelUses) []inUseLabel {
+	var inUse []inUseLabel
+	for _, label := range summary.ToDelete {
+		if uses := usesOf(usage, label.Name); uses.inUse() {
+			inUse = append(inUse, inUseLabel{Label: label, Uses: uses})
+		}
+	}
+	return inUse
+}
+
+// Keeps labels that are still on issues or PRs instead of deleting them.
+// If usage can't be checked, every delete is kept and the error returned.
+func keepInUse(repo string, summary ActionSummary) (ActionSummary, []inUseLabel, error) {
+	if len(summary.ToDelete) == 0 {
+		return summary, nil, nil
+	}
+
+	usage, err := fetchUsage(repo, summary.ToDelete)
+	inUse := inUseDeletes(summary, usage)
+	if len(inUse) == 0 {
+		return summary, nil, err
+	}
+
+	var deletes []Label
+	for _, label := range summary.ToDelete {
+		if !usesOf(usage, label.Name).inUse() {
+			deletes = append(deletes, label)
+		}
+	}
+	summary.ToDelete = deletes
+	if summary.ToDelete == nil {
+		summary.ToDelete = []Label{}
+	}
+	for _, label := range inUse {
+		summary.ToKeep = append(summary.ToKeep, label.Label)
+	}
+	return summary, inUse, err
+}
+
+// Keeps in-use labels unless --delete-in-use is set, and says so on w
+func guardInUse(repo string, summary ActionSummary, w io.Writer) ActionSummary {
+	if deleteInUse {
+		return summary
+	}
+
+	summary, inUse, err := keepInUse(repo, summary)
+	printInUseWarning(w, repo, inUse, err)
+	return summary
+}
+
+// Reports the labels keepInUse kept
+func printInUseWarning(w io.Writer, repo string, inUse []inUseLabel, err error) {
+	if err != nil {
+		fmt.Fprintf(w, "Warning: couldn't count label usage in %s: %v\n", repo, err)
+	}
+	if len(inUse) == 1 {
+		fmt.Fprintf(w, "Keeping 1 label in %s that is in use: %s. Pass --delete-in-use to delete it.\n", repo, formatInUse(inUse))
+	} else if len(inUse) > 1 {
+		fmt.Fprintf(w, "Keeping %d labels in %s that are in use: %s. Pass --delete-in-use to delete them.\n", len(inUse), repo, formatInUse(inUse))
+	}
+}
+
+// Lists in-use labels with their counts, as "bug (3 open issues, 40 closed PRs), ..."
+func formatInUse(inUse []inUseLabel) string {
+	parts := make([]string, 0, len(inUse))
+	for _, label := range inUse {
+		parts = append(parts, fmt.Sprintf("%s (%s)", label.Label.Name, formatUses(label.Uses)))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Describes a label's usage, as "3 open issues, 1 closed issue, 40 closed PRs"
+func formatUses(uses LabelUses) string {
+	if uses.Unknown {
+		return "usage unknown"
+	}
+
+	var parts []string
+	for _, count := range []struct {
+		n    int
+		noun string
+	}{
+		{uses.OpenIssues, "open issue"},
+		{uses.ClosedIssues, "closed issue"},
+		{uses.OpenPRs, "open PR"},
+		{uses.ClosedPRs, "closed PR"},
+	} {
+		switch {
+		case count.n == 1:
+			parts = append(parts, "1 "+count.noun)
+		case count.n > 1:
+			parts = append(parts, fmt.Sprintf("%d %ss", count.n, count.noun))
+		}
+	}
+	if len(parts) == 0 {
+		return "unused"
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestUsageQuery(t *testing.T) {
+	query, variables, err := usageQuery("owner/repo", []string{"bug", "help wanted"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"$l1: String!",
+		"l0: label(name: $l0)",
+		"openIssues: issues(states: [OPEN])",
+		"closedIssues: issues(states: [CLOSED])",
+		"openPRs: pullRequests(states: [OPEN])",
+		"closedPRs: pullRequests(states: [CLOSED, MERGED])",
+	} {
+		if !strings.Contains(query, want) {
+			t.Errorf("Query should contain %q, got %s", want, query)
+		}
+	}
+	if variables["owner"] != "owner" || variables["name"] != "repo" || variables["l1"] != "help wanted" {
+		t.Errorf("Unexpected variables %v", variables)
+	}
+
+	if _, _, err := usageQuery("repo", nil); err == nil {
+		t.Error("Expected an error for a repo without an owner")
+	}
+}
+
+func TestDecodeUsage(t *testing.T) {
+	data := []byte(`{"data": {"repository": {
+		"l0": {"openIssues": {"totalCount": 12}, "closedIssues": {"totalCount": 288}, "openPRs": {"totalCount": 0}, "closedPRs": {"totalCount": 40}},
+		"l1": null
+	}}}`)
+
+	usage, err := decodeUsage(data, []string{"bug", "gone"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := LabelUses{OpenIssues: 12, ClosedIssues: 288, ClosedPRs: 40}
+	if usage["bug"] != want || usage["gone"] != (LabelUses{}) {
+		t.Errorf("Usage = %+v, want bug=%+v and gone unused", usage, want)
+	}
+
+	_, err = decodeUsage([]byte(`{"errors": [{"message": "Could not resolve to a Repository"}]}`), []string{"bug"})
+	if err == nil || !strings.Contains(err.Error(), "Could not resolve") {
+		t.Errorf("Expected the GraphQL error, got %v", err)
+	}
+}
+
+func TestRESTStoreLabelUsage(t *testing.T) {
+	for _, tt := range []struct {
+		base string
+		path string
+	}{
+		{"", "/graphql"},
+		{"/api/v3", "/api/graphql"},
+	} {
+		var path string
+		var body struct {
+			Query     string            `json:"query"`
+			Variables map[string]string `json:"variables"`
+		}
+
+		s := newTestRESTStore(t, func(w http.ResponseWriter, r *http.Request) {
+			path = r.URL.Path
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			_, _ = w.Write([]byte(`{"data": {"repository": {"l0": {"openIssues": {"totalCount": 2}, "openPRs": {"totalCount": 1}}}}}`))
+		})
+		s.baseURL += tt.base
+
+		usage, err := s.LabelUsage("owner/repo", []string{"bug"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if path != tt.path {
+			t.Errorf("GraphQL path = %q, want %q", path, tt.path)
+		}
+		if body.Variables["l0"] != "bug" || usage["bug"].Total() != 3 {
+			t.Errorf("Unexpected variables %v or usage %v", body.Variables, usage)
+		}
+	}
+}
+
+func TestKeepInUse(t *testing.T) {
+	ms := useMemStore(t)
+	ms.usage["owner/repo"] = map[string]LabelUses{"bug": {OpenIssues: 12, ClosedIssues: 288, ClosedPRs: 40}}
+
+	summary := ActionSummary{
+		ToDelete: []Label{{Name: "bug"}, {Name: "stale"}},
+		ToKeep:   []Label{},
+	}
+
+	summary, inUse, err := keepInUse("owner/repo", summary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(summary.ToDelete) != 1 || summary.ToDelete[0].Name != "stale" {
+		t.Errorf("Only the unused label should be deleted, got %v", summary.ToDelete)
+	}
+	if len(summary.ToKeep) != 1 || len(inUse) != 1 || inUse[0].Uses.Total() != 340 {
+		t.Errorf("bug should be kept as in use, got keep=%v inUse=%v", summary.ToKeep, inUse)
+	}
+	if got := formatInUse(inUse); got != "bug (12 open issues, 288 closed issues, 40 closed PRs)" {
+		t.Errorf("formatInUse() = %q", got)
+	}
+}
+
+func TestKeepInUseWithoutUsage(t *testing.T) {
+	ms := useMemStore(t)
+	ms.fail["owner/repo"] = errors.New("GraphQL is disabled")
+
+	summary, inUse, err := keepInUse("owner/repo", ActionSummary{ToDelete: []Label{{Name: "stale"}}})
+	if err == nil {
+		t.Error("Expected the usage error")
+	}
+	if len(summary.ToDelete) != 0 || len(inUse) != 1 || !inUse[0].Uses.Unknown {
+		t.Errorf("Unknown usage should keep every label, got delete=%v inUse=%v", summary.ToDelete, inUse)
+	}
+}
+
+func TestSetItemUsage(t *testing.T) {
+	items := []PickerItem{
+		{Label: Label{Name: "Bug"}, IsDestOnly: true},
+		{Label: Label{Name: "wontfix"}, IsDestOnly: true},
+		{Label: Label{Name: "docs"}},
+	}
+
+	setItemUsage(items, map[string]LabelUses{"bug": {OpenIssues: 12}})
+	if items[0].Uses.OpenIssues != 12 || !items[1].Uses.Unknown || items[2].Uses != (LabelUses{}) {
+		t.Errorf("Uses = %+v, %+v, %+v; want 12 open issues, unknown, unset", items[0].Uses, items[1].Uses, items[2].Uses)
+	}
+}
+
+func TestLabeledIssuesQuery(t *testing.T) {
+	query, variables, err := labeledIssuesQuery("owner/repo", "a, b", "pullRequests", "c1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"label(name: $label)", "items: pullRequests(first: 100, after: $cursor)", "$cursor: String!"} {
+		if !strings.Contains(query, want) {
+			t.Errorf("Query should contain %q, got %s", want, query)
+		}
+	}
+	if variables["label"] != "a, b" || variables["cursor"] != "c1" {
+		t.Errorf("Unexpected variables %v", variables)
+	}
+
+	issues, next, err := decodeLabeledIssues([]byte(`{"data": {"repository": {"label": null}}}`))
+	if err != nil || len(issues) != 0 || next != "" {
+		t.Errorf("A missing label should have no issues, got %v %q %v", issues, next, err)
+	}
+}
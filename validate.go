@@ -19,6 +19,11 @@ func validateColor(color string) (string, error) {
 	return color, nil
 }
 
+// Normalizes a hex color for comparison
+func normalizeColor(color string) string {
+	return strings.ToLower(strings.TrimPrefix(color, "#"))
+}
+
 // Validates a label before creation
 func validateLabel(label Label) error {
 	if strings.TrimSpace(label.Name) == "" {
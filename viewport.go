@@ -0,0 +1,64 @@
+package main
+
+import (
+	"os"
+	"regexp"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+// Matches the escape sequences that color text, which take no space
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]`)
+
+// Returns the terminal's width and height, or zeros if its size is
+// unknown and every row should be shown
+func terminalSize() (width, height int) {
+	width, height, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || width <= 0 || height <= 0 {
+		return 0, 0
+	}
+	return width, height
+}
+
+// Returns how many terminal rows a line takes once it wraps at width
+func lineRows(line string, width int) int {
+	if width <= 0 {
+		return 1
+	}
+	columns := 0
+	for _, r := range ansiEscape.ReplaceAllString(line, "") {
+		columns += runeColumns(r)
+	}
+	return max((columns+width-1)/width, 1)
+}
+
+// Returns how many columns a rune takes: two for wide East Asian
+// characters and most emoji, one for everything else
+func runeColumns(r rune) int {
+	switch {
+	case r == utf8.RuneError:
+		return 1
+	case r >= 0x1100 && r <= 0x115f,
+		r >= 0x2e80 && r <= 0xa4cf,
+		r >= 0xac00 && r <= 0xd7a3,
+		r >= 0xf900 && r <= 0xfaff,
+		r >= 0xfe30 && r <= 0xfe4f,
+		r >= 0xff00 && r <= 0xff60,
+		r >= 0xffe0 && r <= 0xffe6,
+		r >= 0x1f300 && r <= 0x1f64f,
+		r >= 0x1f900 && r <= 0x1f9ff,
+		r >= 0x20000 && r <= 0x3fffd:
+		return 2
+	}
+	return 1
+}
+
+// Returns how many terminal rows some lines take
+func linesRows(lines []string, width int) int {
+	rows := 0
+	for _, line := range lines {
+		rows += lineRows(line, width)
+	}
+	return rows
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestLineRows(t *testing.T) {
+	tests := []struct {
+		line  string
+		width int
+		want  int
+	}{
+		{"", 80, 1},
+		{"short", 0, 1},
+		{strings.Repeat("x", 80), 80, 1},
+		{strings.Repeat("x", 81), 80, 2},
+		{"\033[38;2;215;58;74m█\033[0m bug #d73a4a", 13, 1},
+		{"バグ修正", 4, 2},
+	}
+
+	for _, tt := range tests {
+		if got := lineRows(tt.line, tt.width); got != tt.want {
+			t.Errorf("lineRows(%q, %d) = %d, want %d", tt.line, tt.width, got, tt.want)
+		}
+	}
+}
+
+func TestPickerFrameFitsTerminal(t *testing.T) {
+	var items []PickerItem
+	for i := 0; i < 40; i++ {
+		item := PickerItem{
+			Label:    Label{Name: fmt.Sprintf("label-%02d", i), Color: "ededed", Description: strings.Repeat("A long description ", 4)},
+			Selected: true,
+		}
+		switch i % 3 {
+		case 0:
+			item.IsDestOnly = true
+		case 1:
+			item.Differs = true
+			item.Current = Label{Name: item.Label.Name, Color: "ffffff"}
+		}
+		items = append(items, item)
+	}
+
+	// Each script ends in a different state: scrolled, filtering,
+	// showing a message, merging and editing
+	scripts := map[string]string{
+		"scrolled": strings.Repeat("\x1b[B", 25),
+		"filter":   strings.Repeat("\x1b[B", 10) + "/label-1",
+		"message":  "\x1b[F\x1b[Am",
+		"merge":    "\x1b[F\x1b[Hmlabel-0",
+		"edit":     "\x1b[F\x1b[Ae",
+	}
+
+	for _, width := range []int{40, 80, 200} {
+		for height := 16; height <= 40; height += 3 {
+			for _, verbose := range []bool{false, true} {
+				for name, script := range scripts {
+					m := newPickerModel(items, "owner/repo", verbose)
+					m.setTermSize(width, height)
+					pressKeys(m, script)
+					if (name == "merge") != m.merging || (name == "message") != (m.message != "") {
+						t.Fatalf("%s: the script ended in the wrong state", name)
+					}
+
+					frame := strings.TrimPrefix(m.view(), "\033[2J\033[H")
+					lines := strings.Split(frame, "\n")
+					// A terminal too short for the title, the keys and a
+					// row with its scroll markers can't fit the frame
+					chrome := linesRows(m.headerLines(), width) + linesRows(m.footerLines(), width)
+					rowRows := linesRows(m.rowLines(m.cursor), width)
+					if rows := linesRows(lines, width); rows > height && chrome+rowRows+2 <= height {
+						t.Errorf("%s at %dx%d (verbose %v): frame takes %d rows:\n%s", name, width, height, verbose, rows, frame)
+					}
+					if !strings.HasPrefix(lines[0], "Current state") {
+						t.Errorf("%s at %dx%d: the title should be the first line", name, width, height)
+					}
+					if !strings.Contains(frame, "> [") {
+						t.Errorf("%s at %dx%d: the cursor row should be drawn", name, width, height)
+					}
+				}
+			}
+		}
+	}
+}